@@ -2,7 +2,7 @@ package analyze
 
 import (
 	"context"
-	"fmt"
+	"encoding/json"
 	"japaneseparse/ingest"
 	"japaneseparse/model"
 )
@@ -12,10 +12,15 @@ type LexEntry = model.LexEntry
 // Analysis represents the result of analyzing a sentence plus lexicon entries.
 type Analysis struct {
 	SentenceID    string      `json:"sentence_id"`
+	DocumentID    string      `json:"document_id,omitempty"`
+	Paragraph     int         `json:"paragraph,omitempty"`
+	Index         int         `json:"index,omitempty"` // index of the sentence in its document
 	TokenCount    int         `json:"token_count"`
 	Definitions   int         `json:"definitions_found"`
 	GrammarIssues []string    `json:"grammar_issues,omitempty"`
 	Structure     interface{} `json:"structure,omitempty"`
+	Quantities    []Quantity  `json:"quantities,omitempty"`
+	Temporals     []Temporal  `json:"temporals,omitempty"`
 }
 
 // SemanticRole represents semantic roles in a clause.
@@ -63,11 +68,32 @@ type Clause struct {
 	Connective string     `json:"connective,omitempty"`
 }
 
+// Clauses returns the clauses recorded in Structure. It also handles a Structure that
+// was decoded from JSON, such as a cached analysis.
+func (a Analysis) Clauses() []Clause {
+	m, ok := a.Structure.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	switch c := m["clauses"].(type) {
+	case []Clause:
+		return c
+	case nil:
+		return nil
+	default:
+		var clauses []Clause
+		b, err := json.Marshal(c)
+		if err != nil || json.Unmarshal(b, &clauses) != nil {
+			return nil
+		}
+		return clauses
+	}
+}
+
 // Analyze performs grammar/structure analysis over the lexicon entries.
 func Analyze(ctx context.Context, sentence ingest.Sentence, entries []LexEntry) (Analysis, error) {
-	if ctx.Err() != nil {
-		fmt.Println("[ANALYZE] Context error:", ctx.Err())
-		// Log and continue instead of returning
+	if err := ctx.Err(); err != nil {
+		return Analysis{}, err
 	}
 
 	found := 0
@@ -109,11 +135,24 @@ func Analyze(ctx context.Context, sentence ingest.Sentence, entries []LexEntry)
 	// For each clause, assign grammatical roles
 	// ...existing code for grammatical role assignment...
 
+	// Entity extraction over the sentence tokens
+	tokens := make([]model.Token, len(entries))
+	for i, e := range entries {
+		tokens[i] = e.Token
+	}
+	quantities := ExtractQuantities(tokens)
+	temporals := ExtractTemporals(tokens, sentence.CreatedAt)
+
 	return Analysis{
 		SentenceID:    sentence.ID,
+		DocumentID:    sentence.DocumentID,
+		Paragraph:     sentence.Paragraph,
+		Index:         sentence.Index,
 		TokenCount:    len(entries),
 		Definitions:   found,
 		GrammarIssues: []string{},
 		Structure:     map[string]interface{}{"clauses": clauses},
+		Quantities:    quantities,
+		Temporals:     temporals,
 	}, nil
 }
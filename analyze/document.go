@@ -0,0 +1,46 @@
+package analyze
+
+import "japaneseparse/ingest"
+
+// DocumentAnalysis groups sentence analyses by the paragraphs of their document.
+type DocumentAnalysis struct {
+	DocumentID string              `json:"document_id"`
+	Metadata   ingest.Metadata     `json:"metadata"`
+	Paragraphs []ParagraphAnalysis `json:"paragraphs"`
+}
+
+// ParagraphAnalysis holds the analyses of one paragraph in sentence order.
+type ParagraphAnalysis struct {
+	Index     int        `json:"index"`
+	Sentences []Analysis `json:"sentences"`
+}
+
+// sentenceKey identifies a sentence within its document. The ID alone is not enough:
+// with content-derived IDs a repeated sentence shares the ID of its first occurrence.
+type sentenceKey struct {
+	id               string
+	paragraph, index int
+}
+
+// GroupByDocument arranges analyses under the paragraphs of doc in document order.
+// Analyses are matched to sentences by ID, paragraph and index. Analyses of sentences
+// that are not part of doc are ignored; sentences without an analysis are skipped.
+func GroupByDocument(doc ingest.Document, analyses []Analysis) DocumentAnalysis {
+	byKey := make(map[sentenceKey]Analysis, len(analyses))
+	for _, a := range analyses {
+		if a.DocumentID == doc.ID {
+			byKey[sentenceKey{a.SentenceID, a.Paragraph, a.Index}] = a
+		}
+	}
+	out := DocumentAnalysis{DocumentID: doc.ID, Metadata: doc.Metadata}
+	for _, p := range doc.Paragraphs {
+		pa := ParagraphAnalysis{Index: p.Index}
+		for _, s := range p.Sentences {
+			if a, ok := byKey[sentenceKey{s.ID, s.Paragraph, s.Index}]; ok {
+				pa.Sentences = append(pa.Sentences, a)
+			}
+		}
+		out.Paragraphs = append(out.Paragraphs, pa)
+	}
+	return out
+}
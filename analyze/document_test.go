@@ -0,0 +1,43 @@
+package analyze
+
+import (
+	"testing"
+
+	"japaneseparse/ingest"
+)
+
+func TestGroupByDocumentRepeatedSentences(t *testing.T) {
+	ingest.ContentIDs = true
+	defer func() { ingest.ContentIDs = false }()
+	doc, err := ingest.NewDocument("はい。いいえ。はい。\n\nはい。", ingest.Metadata{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// one analysis per sentence, in reverse to show that order does not matter; the
+	// token count records which sentence it belongs to
+	var analyses []Analysis
+	for pi := len(doc.Paragraphs) - 1; pi >= 0; pi-- {
+		ss := doc.Paragraphs[pi].Sentences
+		for si := len(ss) - 1; si >= 0; si-- {
+			s := ss[si]
+			analyses = append(analyses, Analysis{SentenceID: s.ID, DocumentID: doc.ID, Paragraph: s.Paragraph, Index: s.Index, TokenCount: s.Index})
+		}
+	}
+	if doc.Paragraphs[0].Sentences[0].ID != doc.Paragraphs[1].Sentences[0].ID {
+		t.Fatal("repeated sentences should share a content ID")
+	}
+
+	got := GroupByDocument(doc, analyses)
+	if len(got.Paragraphs) != 2 {
+		t.Fatalf("got %d paragraphs, want 2", len(got.Paragraphs))
+	}
+	var indexes []int
+	for _, p := range got.Paragraphs {
+		for _, a := range p.Sentences {
+			indexes = append(indexes, a.TokenCount)
+		}
+	}
+	if len(indexes) != 4 || indexes[0] != 0 || indexes[1] != 1 || indexes[2] != 2 || indexes[3] != 3 {
+		t.Errorf("sentences grouped as %v, want [0 1 2 3]", indexes)
+	}
+}
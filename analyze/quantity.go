@@ -0,0 +1,126 @@
+package analyze
+
+import (
+	"strings"
+
+	"japaneseparse/model"
+	"japaneseparse/numerals"
+)
+
+// Quantity is a number+counter expression such as 約300人以上.
+type Quantity struct {
+	Text        string `json:"text"`
+	Value       int64  `json:"value"`
+	Counter     string `json:"counter,omitempty"`
+	Category    string `json:"category"`              // what the counter measures, "number" without a counter
+	Approximate bool   `json:"approximate,omitempty"` // preceded by 約/およそ
+	Comparator  string `json:"comparator,omitempty"`  // gte (以上), lte (以下), lt (未満), gt (超)
+	Start       int    `json:"start"`                 // index of the first token
+	End         int    `json:"end"`                   // index one past the last token
+}
+
+var approximators = map[string]bool{"約": true, "およそ": true, "凡そ": true}
+
+var comparators = map[string]string{"以上": "gte", "以下": "lte", "未満": "lt", "超": "gt"}
+
+// ExtractQuantities finds quantity expressions in tokens: an optional 約/およそ, a
+// numeral, an optional counter and an optional 以上/以下/未満/超. It accepts both raw
+// kagome tokens ("283", "世帯") and tokens merged by tokenize.MergeNumberCounters
+// ("283世帯").
+func ExtractQuantities(tokens []model.Token) []Quantity {
+	var out []Quantity
+	i := 0
+	for i < len(tokens) {
+		q, next, ok := matchQuantity(tokens, i)
+		if !ok {
+			i++
+			continue
+		}
+		out = append(out, q)
+		i = next
+	}
+	return out
+}
+
+// matchQuantity tries to match a quantity starting at token i and returns it together
+// with the index after it.
+func matchQuantity(tokens []model.Token, i int) (Quantity, int, bool) {
+	q := Quantity{Start: i}
+	j := i
+	if approximators[tokens[j].Text] {
+		q.Approximate = true
+		j++
+	}
+	if j >= len(tokens) {
+		return Quantity{}, 0, false
+	}
+
+	numText, counter := "", ""
+	if isNumeralToken(tokens[j]) {
+		for j < len(tokens) && isNumeralToken(tokens[j]) {
+			numText += tokens[j].Text
+			j++
+		}
+		if j < len(tokens) && numerals.IsCounter(tokens[j].Text) {
+			counter = tokens[j].Text
+			j++
+		}
+	} else if num, rest := numerals.SplitNumeralPrefix(tokens[j].Text); num != "" && (rest == "" || numerals.IsCounter(rest)) && isNumericPOS(tokens[j]) {
+		// merged number+counter token
+		numText, counter = num, rest
+		j++
+	} else {
+		return Quantity{}, 0, false
+	}
+
+	value, ok := numerals.Parse(numText)
+	if !ok {
+		return Quantity{}, 0, false
+	}
+	q.Value = value
+	q.Counter = counter
+	q.Category = "number"
+	if counter != "" {
+		q.Category = numerals.CounterCategory(counter)
+	}
+	if counter == "日" {
+		q.Category = dayCategory(tokens, i, j)
+	}
+	if j < len(tokens) {
+		if c, ok := comparators[tokens[j].Text]; ok {
+			q.Comparator = c
+			j++
+		}
+	}
+	q.End = j
+	var sb strings.Builder
+	for _, t := range tokens[q.Start:q.End] {
+		sb.WriteString(t.Text)
+	}
+	q.Text = sb.String()
+	return q, j, true
+}
+
+// dayCategory tells a number of days (三日かかった) from a day of the month (5月3日,
+// 3日に) for a 日 quantity spanning tokens[start:end]. 日間 is always a duration.
+func dayCategory(tokens []model.Token, start, end int) string {
+	if start > 0 && strings.HasSuffix(tokens[start-1].Text, "月") {
+		return "date"
+	}
+	if end < len(tokens) && strings.HasPrefix(tokens[end].POS, "動詞") {
+		return "duration"
+	}
+	return "date"
+}
+
+// isNumeralToken reports whether t is a bare numeral token (名詞,数).
+func isNumeralToken(t model.Token) bool {
+	return strings.HasPrefix(t.POS, "名詞,数") && numerals.IsNumeral(t.Text)
+}
+
+// isNumericPOS accepts numeral tokens and tokens merged by tokenize.MergeNumberCounters;
+// it keeps lexicalized words like 十分 (adjectival noun) and 一番 (adverbial noun) from
+// being read as quantities.
+func isNumericPOS(t model.Token) bool {
+	return strings.HasPrefix(t.POS, "名詞,数") || len(t.MergedIndices) > 0
+}
@@ -0,0 +1,53 @@
+package analyze
+
+import (
+	"context"
+	"testing"
+
+	"japaneseparse/tokenize"
+)
+
+func TestExtractQuantities(t *testing.T) {
+	cases := []struct {
+		text  string
+		merge bool
+		want  []Quantity // Text, Value, Counter, Category, Approximate and Comparator are compared
+	}{
+		{"約300人以上が来た。", false, []Quantity{{Text: "約300人以上", Value: 300, Counter: "人", Category: "people", Approximate: true, Comparator: "gte"}}},
+		{"約300人以上が来た。", true, []Quantity{{Text: "約300人以上", Value: 300, Counter: "人", Category: "people", Approximate: true, Comparator: "gte"}}},
+		{"3人未満だった。", false, []Quantity{{Text: "3人未満", Value: 3, Counter: "人", Category: "people", Comparator: "lt"}}},
+		{"百円だ。", true, []Quantity{{Text: "百円", Value: 100, Counter: "円", Category: "currency"}}},
+		// 日 counts days before a verb and names a day after a month
+		{"三日かかった。", false, []Quantity{{Text: "三日", Value: 3, Counter: "日", Category: "duration"}}},
+		{"三日かかった。", true, []Quantity{{Text: "三日", Value: 3, Counter: "日", Category: "duration"}}},
+		{"三日間休んだ。", false, []Quantity{{Text: "三日間", Value: 3, Counter: "日間", Category: "duration"}}},
+		{"5月3日に会う。", false, []Quantity{{Text: "5月", Value: 5, Counter: "月", Category: "date"}, {Text: "3日", Value: 3, Counter: "日", Category: "date"}}},
+		{"5月3日に会う。", true, []Quantity{{Text: "5月", Value: 5, Counter: "月", Category: "date"}, {Text: "3日", Value: 3, Counter: "日", Category: "date"}}},
+		{"3日に会う。", false, []Quantity{{Text: "3日", Value: 3, Counter: "日", Category: "date"}}},
+		// lexicalized words that start with a numeral are not quantities
+		{"十分に休んだ。", false, nil},
+		{"一番好きだ。", false, nil},
+		{"一番好きだ。", true, nil},
+	}
+	for _, c := range cases {
+		toks, err := tokenize.Tokenize(context.Background(), c.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.merge {
+			toks = tokenize.MergeNumberCounters(toks)
+		}
+		got := ExtractQuantities(toks)
+		if len(got) != len(c.want) {
+			t.Errorf("%s (merge=%v): got %+v, want %+v", c.text, c.merge, got, c.want)
+			continue
+		}
+		for i, w := range c.want {
+			g := got[i]
+			if g.Text != w.Text || g.Value != w.Value || g.Counter != w.Counter || g.Category != w.Category ||
+				g.Approximate != w.Approximate || g.Comparator != w.Comparator {
+				t.Errorf("%s (merge=%v): got %+v, want %+v", c.text, c.merge, g, w)
+			}
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package analyze
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"japaneseparse/model"
+	"japaneseparse/numerals"
+)
+
+// Temporal is a time expression normalized to ISO-8601.
+type Temporal struct {
+	Text     string `json:"text"`
+	Type     string `json:"type"`               // date, month, year, week, time or datetime
+	Value    string `json:"value"`              // ISO-8601: 2023-07-01, 2023-07, 2026-W41, T08:40, ...
+	Relative bool   `json:"relative,omitempty"` // resolved against the sentence time
+	Start    int    `json:"start"`              // index of the first token
+	End      int    `json:"end"`                // index one past the last token
+}
+
+// era is a Japanese era: the Gregorian year of its first year (元年) and its last
+// year number, 0 for the current era.
+type era struct{ start, last int }
+
+var eras = map[string]era{
+	"明治": {1868, 45},
+	"大正": {1912, 15},
+	"昭和": {1926, 64},
+	"平成": {1989, 31},
+	"令和": {2019, 0},
+}
+
+// jst is used to resolve relative expressions; news "today" is a Japanese day.
+var jst = time.FixedZone("JST", 9*60*60)
+
+const (
+	numClass = `[0-9０-９〇零一二三四五六七八九十百千]+`
+	numeral  = `(` + numClass + `)`
+)
+
+var (
+	eraDateRe  = regexp.MustCompile(`^(明治|大正|昭和|平成|令和)(元|` + numClass + `)年(?:` + numeral + `月(?:` + numeral + `日)?)?`)
+	fullDateRe = regexp.MustCompile(`^` + numeral + `年` + numeral + `月(?:` + numeral + `日)?`)
+	monthDayRe = regexp.MustCompile(`^` + numeral + `月` + numeral + `日`)
+	yearRe     = regexp.MustCompile(`^` + numeral + `年`)
+	timeRe     = regexp.MustCompile(`^(午前|午後)?` + numeral + `時(?:(半)|` + numeral + `分)?`)
+)
+
+// relativeDays, relativeWeeks, relativeMonths and relativeYears are offsets from the
+// sentence time.
+var (
+	relativeDays   = map[string]int{"一昨日": -2, "昨日": -1, "今日": 0, "本日": 0, "今朝": 0, "今夜": 0, "今晩": 0, "明日": 1, "明後日": 2}
+	relativeWeeks  = map[string]int{"先々週": -2, "先週": -1, "今週": 0, "来週": 1, "再来週": 2}
+	relativeMonths = map[string]int{"先々月": -2, "先月": -1, "今月": 0, "来月": 1, "再来月": 2}
+	relativeYears  = map[string]int{"一昨年": -2, "去年": -1, "昨年": -1, "今年": 0, "本年": 0, "来年": 1, "再来年": 2}
+)
+
+// ExtractTemporals finds dates, times and relative expressions in tokens and normalizes
+// them to ISO-8601. Relative expressions (昨日, 先週) and dates without a year are
+// resolved against ref in Japan time; with a zero ref they are skipped. Wareki years
+// (令和5年, 平成元年) are converted to Gregorian years. Expressions must start and end on
+// token boundaries, so 明日 is not found in 明日香, and impossible dates (2月31日, 平成40年)
+// are ignored.
+func ExtractTemporals(tokens []model.Token, ref time.Time) []Temporal {
+	ref = ref.In(jst)
+
+	// work on the joined text and map byte offsets back to token indices
+	var sb strings.Builder
+	offsets := make([]int, len(tokens)+1)
+	for i, t := range tokens {
+		offsets[i] = sb.Len()
+		sb.WriteString(t.Text)
+	}
+	offsets[len(tokens)] = sb.Len()
+	text := sb.String()
+	boundary := make(map[int]bool, len(offsets))
+	for _, o := range offsets {
+		boundary[o] = true
+	}
+
+	// expressions may only start and end at a token boundary
+	var out []Temporal
+	for i := 0; i < len(tokens); {
+		pos := offsets[i]
+		tm, n := matchTemporal(text[pos:], ref, func(n int) bool { return boundary[pos+n] })
+		if n == 0 {
+			i++
+			continue
+		}
+		end := tokenAt(offsets, pos+n-1) + 1
+		if tm.Type != "" {
+			tm.Text = text[pos : pos+n]
+			tm.Start, tm.End = i, end
+			out = append(out, tm)
+		}
+		i = end
+	}
+	return out
+}
+
+// matchTemporal matches one temporal expression at the start of s and returns it with
+// the number of bytes consumed (0 if nothing matched). Matches must end where ends
+// reports a token boundary. A date followed directly by a time becomes a datetime; an
+// impossible date is returned with an empty Type so the caller skips it.
+func matchTemporal(s string, ref time.Time, ends func(int) bool) (Temporal, int) {
+	tm, n := matchDate(s, ref)
+	if n > 0 {
+		if tm.Type == "" {
+			return tm, n
+		}
+		if tm.Type == "date" {
+			if clock, m := matchTime(s[n:]); m > 0 && ends(n+m) {
+				tm.Type = "datetime"
+				tm.Value += clock
+				return tm, n + m
+			}
+		}
+		if ends(n) {
+			return tm, n
+		}
+		return Temporal{}, 0
+	}
+	if clock, m := matchTime(s); m > 0 && ends(m) {
+		return Temporal{Type: "time", Value: clock}, m
+	}
+	return Temporal{}, 0
+}
+
+// matchDate matches a date at the start of s. Dates that need ref are not matched
+// when ref is zero.
+func matchDate(s string, ref time.Time) (Temporal, int) {
+	if m := eraDateRe.FindStringSubmatch(s); m != nil {
+		year := 1
+		if m[2] != "元" {
+			y, ok := parseNumber(m[2])
+			if !ok {
+				return Temporal{}, 0
+			}
+			year = y
+		}
+		e := eras[m[1]]
+		if year < 1 || e.last > 0 && year > e.last {
+			return Temporal{}, len(m[0])
+		}
+		return dateFromParts(e.start+year-1, m[3], m[4], false, len(m[0]))
+	}
+	if m := fullDateRe.FindStringSubmatch(s); m != nil {
+		if y, ok := parseNumber(m[1]); ok {
+			return dateFromParts(y, m[2], m[3], false, len(m[0]))
+		}
+	}
+	if m := yearRe.FindStringSubmatch(s); m != nil {
+		// a bare number+年 is a year only if it looks like one (2023年, not 5年)
+		if y, ok := parseNumber(m[1]); ok && y >= 1000 {
+			return Temporal{Type: "year", Value: fmt.Sprintf("%04d", y)}, len(m[0])
+		}
+	}
+	if ref.IsZero() {
+		return Temporal{}, 0
+	}
+	if m := monthDayRe.FindStringSubmatch(s); m != nil {
+		return dateFromParts(ref.Year(), m[1], m[2], true, len(m[0]))
+	}
+	if n, d := matchWord(s, relativeDays); n > 0 {
+		return Temporal{Type: "date", Value: ref.AddDate(0, 0, d).Format("2006-01-02"), Relative: true}, n
+	}
+	if n, w := matchWord(s, relativeWeeks); n > 0 {
+		y, wk := ref.AddDate(0, 0, 7*w).ISOWeek()
+		return Temporal{Type: "week", Value: fmt.Sprintf("%04d-W%02d", y, wk), Relative: true}, n
+	}
+	if n, mo := matchWord(s, relativeMonths); n > 0 {
+		first := time.Date(ref.Year(), ref.Month(), 1, 0, 0, 0, 0, jst).AddDate(0, mo, 0)
+		return Temporal{Type: "month", Value: first.Format("2006-01"), Relative: true}, n
+	}
+	if n, y := matchWord(s, relativeYears); n > 0 {
+		return Temporal{Type: "year", Value: fmt.Sprintf("%04d", ref.Year()+y), Relative: true}, n
+	}
+	return Temporal{}, 0
+}
+
+// dateFromParts builds a year, month or date value from a year and optional month/day
+// numerals, passing n through as the match length. Impossible months and days such as
+// 13月 or 2月30日 give an empty Temporal, so the whole expression is skipped.
+func dateFromParts(year int, month, day string, relative bool, n int) (Temporal, int) {
+	if month == "" {
+		return Temporal{Type: "year", Value: fmt.Sprintf("%04d", year), Relative: relative}, n
+	}
+	m, ok := parseNumber(month)
+	if !ok || m < 1 || m > 12 {
+		return Temporal{}, n
+	}
+	if day == "" {
+		return Temporal{Type: "month", Value: fmt.Sprintf("%04d-%02d", year, m), Relative: relative}, n
+	}
+	d, ok := parseNumber(day)
+	// time.Date normalizes 2月30日 to 3月2日; a changed month means the day does not exist
+	if !ok || d < 1 || time.Date(year, time.Month(m), d, 0, 0, 0, 0, time.UTC).Month() != time.Month(m) {
+		return Temporal{}, n
+	}
+	return Temporal{Type: "date", Value: fmt.Sprintf("%04d-%02d-%02d", year, m, d), Relative: relative}, n
+}
+
+// matchTime matches 午前8時40分 / 午後3時半 / 8時 and returns "T08:40".
+func matchTime(s string) (string, int) {
+	m := timeRe.FindStringSubmatch(s)
+	if m == nil || strings.HasPrefix(s[len(m[0]):], "間") {
+		// 8時間 is a duration, not a time of day
+		return "", 0
+	}
+	hour, ok := parseNumber(m[2])
+	if !ok || hour > 24 {
+		return "", 0
+	}
+	minute := 0
+	if m[3] == "半" {
+		minute = 30
+	} else if m[4] != "" {
+		if minute, ok = parseNumber(m[4]); !ok || minute > 59 {
+			return "", 0
+		}
+	}
+	switch m[1] {
+	case "午前":
+		if hour == 12 {
+			hour = 0
+		}
+	case "午後":
+		if hour < 12 {
+			hour += 12
+		}
+	}
+	return fmt.Sprintf("T%02d:%02d", hour, minute), len(m[0])
+}
+
+func parseNumber(s string) (int, bool) {
+	v, ok := numerals.Parse(s)
+	return int(v), ok
+}
+
+// matchWord returns the byte length and value of the longest key of words that
+// prefixes s, so 一昨日 wins over 昨日 regardless of map order.
+func matchWord(s string, words map[string]int) (int, int) {
+	best, value := 0, 0
+	for w, v := range words {
+		if strings.HasPrefix(s, w) && len(w) > best {
+			best, value = len(w), v
+		}
+	}
+	return best, value
+}
+
+// tokenAt returns the index of the token containing byte offset b.
+func tokenAt(offsets []int, b int) int {
+	for i := 0; i < len(offsets)-1; i++ {
+		if b >= offsets[i] && b < offsets[i+1] {
+			return i
+		}
+	}
+	return len(offsets) - 2
+}
@@ -0,0 +1,73 @@
+package analyze
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"japaneseparse/tokenize"
+)
+
+func TestExtractTemporals(t *testing.T) {
+	ref := time.Date(2024, 5, 1, 12, 0, 0, 0, jst) // a Wednesday
+	cases := []struct {
+		text string
+		want []string // type=value of each temporal, in order
+	}{
+		// wareki conversion
+		{"令和5年に卒業した。", []string{"year=2023"}},
+		{"平成元年に生まれた。", []string{"year=1989"}},
+		{"昭和64年1月7日のことだ。", []string{"date=1989-01-07"}},
+		{"明治45年7月に終わった。", []string{"month=1912-07"}},
+		{"平成40年に完成する。", nil}, // 平成 ended in its 31st year
+		{"大正16年だった。", nil},
+		// absolute dates and times
+		{"2023年7月1日午前8時40分に出発した。", []string{"datetime=2023-07-01T08:40"}},
+		{"2024年2月29日は閏日だ。", []string{"date=2024-02-29"}},
+		{"2023年2月29日は存在しない。", nil},
+		{"2月31日に会おう。", nil},
+		{"13月に会おう。", nil},
+		{"午後3時半に会おう。", []string{"time=T15:30"}},
+		{"8時間寝た。", nil},
+		// relative expressions against ref
+		{"明日は雨だ。", []string{"date=2024-05-02"}},
+		{"一昨日は晴れた。", []string{"date=2024-04-29"}},
+		{"来週また来る。", []string{"week=2024-W19"}},
+		{"先月引っ越した。", []string{"month=2024-04"}},
+		{"去年の夏。", []string{"year=2023"}},
+		{"3月3日に会った。", []string{"date=2024-03-03"}},
+		// matches end on a token boundary
+		{"明日香に会った。", nil},
+	}
+	for _, c := range cases {
+		toks, err := tokenize.Tokenize(context.Background(), c.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, tm := range ExtractTemporals(toks, ref) {
+			got = append(got, tm.Type+"="+tm.Value)
+		}
+		if len(got) != len(c.want) {
+			t.Errorf("%s: got %v, want %v", c.text, got, c.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("%s: got %v, want %v", c.text, got, c.want)
+				break
+			}
+		}
+	}
+}
+
+func TestExtractTemporalsWithoutRef(t *testing.T) {
+	toks, err := tokenize.Tokenize(context.Background(), "令和5年の明日、3月3日に会う。")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := ExtractTemporals(toks, time.Time{})
+	if len(got) != 1 || got[0].Value != "2023" {
+		t.Errorf("got %+v, want only the absolute year 2023", got)
+	}
+}
@@ -0,0 +1,162 @@
+// Package cache stores pipeline results under content-hash keys in an in-memory LRU,
+// optionally backed by a directory of JSON files that survives restarts.
+package cache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DefaultSize is the number of entries kept in memory when New is given no size.
+const DefaultSize = 4096
+
+// Stats reports cache usage.
+type Stats struct {
+	Entries  int    `json:"entries"`   // entries in memory
+	Capacity int    `json:"capacity"`  // maximum entries in memory
+	Hits     uint64 `json:"hits"`      // lookups answered from memory or disk
+	DiskHits uint64 `json:"disk_hits"` // the part of Hits read from disk
+	Misses   uint64 `json:"misses"`
+}
+
+// Cache is safe for concurrent use. Values are stored as JSON, so every Get decodes a
+// fresh copy that callers may modify.
+type Cache struct {
+	mu    sync.Mutex
+	size  int
+	dir   string
+	order *list.List // front is most recently used
+	items map[string]*list.Element
+	stats Stats
+}
+
+type entry struct {
+	key   string
+	value []byte
+}
+
+// New returns a cache holding up to size entries in memory (DefaultSize if size < 1).
+// If dir is not empty, entries are also written there and read back on a memory miss.
+func New(size int, dir string) (*Cache, error) {
+	if size < 1 {
+		size = DefaultSize
+	}
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	return &Cache{size: size, dir: dir, order: list.New(), items: make(map[string]*list.Element)}, nil
+}
+
+// Key hashes parts, e.g. the sentence text, pipeline configuration and dictionary
+// version, into a cache key.
+func Key(parts ...string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Get decodes the value stored under key into v and reports whether it was found.
+func (c *Cache) Get(key string, v any) bool {
+	c.mu.Lock()
+	if el, ok := c.items[key]; ok {
+		c.order.MoveToFront(el)
+		c.stats.Hits++
+		b := el.Value.(*entry).value
+		c.mu.Unlock()
+		return json.Unmarshal(b, v) == nil
+	}
+	c.mu.Unlock()
+
+	if c.dir != "" {
+		if b, err := os.ReadFile(c.path(key)); err == nil && json.Unmarshal(b, v) == nil {
+			c.mu.Lock()
+			c.add(key, b)
+			c.stats.Hits++
+			c.stats.DiskHits++
+			c.mu.Unlock()
+			return true
+		}
+	}
+	c.mu.Lock()
+	c.stats.Misses++
+	c.mu.Unlock()
+	return false
+}
+
+// Put stores v under key in memory and, if the cache has a directory, on disk.
+func (c *Cache) Put(key string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.add(key, b)
+	c.mu.Unlock()
+	if c.dir == "" {
+		return nil
+	}
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// write to a temporary file first so readers never see a partial entry
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// add inserts or refreshes an entry and evicts the least recently used ones. c.mu must
+// be held.
+func (c *Cache) add(key string, b []byte) {
+	if el, ok := c.items[key]; ok {
+		el.Value.(*entry).value = b
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&entry{key: key, value: b})
+	for c.order.Len() > c.size {
+		el := c.order.Back()
+		c.order.Remove(el)
+		delete(c.items, el.Value.(*entry).key)
+	}
+}
+
+// path spreads entries over subdirectories named by the first two hex digits.
+func (c *Cache) path(key string) string {
+	sub := key
+	if len(sub) > 2 {
+		sub = sub[:2]
+	}
+	return filepath.Join(c.dir, sub, key+".json")
+}
+
+// Stats returns the current usage counters.
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st := c.stats
+	st.Entries = c.order.Len()
+	st.Capacity = c.size
+	return st
+}
@@ -0,0 +1,39 @@
+package cache
+
+import "testing"
+
+func TestLRUAndDisk(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(2, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"a", "b", "c"} {
+		if err := c.Put(Key(k), k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if st := c.Stats(); st.Entries != 2 {
+		t.Errorf("entries = %d, want 2", st.Entries)
+	}
+	// "a" was evicted from memory but is still on disk
+	var v string
+	if !c.Get(Key("a"), &v) || v != "a" {
+		t.Errorf("Get(a) = %q", v)
+	}
+	if st := c.Stats(); st.Hits != 1 || st.DiskHits != 1 {
+		t.Errorf("stats = %+v", st)
+	}
+
+	// a new cache over the same directory sees earlier entries
+	c2, err := New(2, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c2.Get(Key("c"), &v) || v != "c" {
+		t.Errorf("Get(c) after reopen = %q", v)
+	}
+	if c2.Get(Key("missing"), &v) {
+		t.Error("Get(missing) found a value")
+	}
+}
@@ -0,0 +1,370 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"japaneseparse/ingest"
+	"japaneseparse/pipeline"
+)
+
+// batchRecord is one output line of batch.
+type batchRecord struct {
+	Line   int              `json:"line"`         // 1-based line number in the input
+	ID     string           `json:"id,omitempty"` // the input record's ID, or the line number
+	Result *pipeline.Result `json:"result,omitempty"`
+	Error  string           `json:"error,omitempty"`
+}
+
+// checkpoint records how far a batch got. Output is written in input order, so every
+// line up to Line has its record in the first Offset bytes of the output.
+type checkpoint struct {
+	Input   string `json:"input"`
+	Output  string `json:"output"`
+	Line    int    `json:"line"`
+	Offset  int64  `json:"offset"`
+	Records int    `json:"records"`
+	Errors  int    `json:"errors"`
+}
+
+// batchOptions are the flags of the batch command.
+type batchOptions struct {
+	inputFormat string // auto, jsonl or text
+	textField   string
+	idField     string
+	output      string
+	checkpoint  string
+	every       int
+	resume      bool
+	onError     string // stop or continue
+	progress    time.Duration
+}
+
+// pendingLine is a submitted line waiting for its result: from the ingest queue, or
+// from future if it was replayed.
+type pendingLine struct {
+	line   int
+	id     string
+	queued bool
+	future *pipeline.Future
+	err    error
+}
+
+func runBatch(e *env, args []string) error {
+	fs := e.newFlagSet("batch", FormatJSONL)
+	var o batchOptions
+	fs.StringVar(&o.inputFormat, "input-format", "auto", "input lines: jsonl, text, or auto (lines starting with { are JSON)")
+	fs.StringVar(&o.textField, "text-field", "text", "field of a JSON input record holding the text")
+	fs.StringVar(&o.idField, "id-field", "id", "field of a JSON input record holding its ID")
+	fs.StringVar(&o.output, "o", "", "output file (default stdout; required for checkpoints)")
+	fs.StringVar(&o.checkpoint, "checkpoint", "", "checkpoint file (default <output>.checkpoint when -o is set)")
+	fs.IntVar(&o.every, "checkpoint-every", 100, "records between checkpoints")
+	fs.BoolVar(&o.resume, "resume", false, "continue after the last checkpoint instead of starting over")
+	fs.StringVar(&o.onError, "on-error", "continue", "on a failed record: continue (write an error record) or stop")
+	fs.DurationVar(&o.progress, "progress", 2*time.Second, "interval between progress reports on stderr; 0 disables")
+	if err := e.parse(fs, args); err != nil {
+		return err
+	}
+	if e.cfg.format != FormatJSONL {
+		return fmt.Errorf("batch writes jsonl only, not %q", e.cfg.format)
+	}
+	switch o.inputFormat {
+	case "auto", "jsonl", "text":
+	default:
+		return fmt.Errorf("unknown input format %q (want auto, jsonl or text)", o.inputFormat)
+	}
+	if o.onError != "continue" && o.onError != "stop" {
+		return fmt.Errorf("unknown -on-error %q (want continue or stop)", o.onError)
+	}
+	if len(fs.Args()) > 0 || len(e.cfg.inputs) > 1 {
+		fmt.Fprintln(e.stderr, "batch: give one input with -i, or none for stdin")
+		return errUsage
+	}
+	if o.checkpoint == "" && o.output != "" {
+		o.checkpoint = o.output + ".checkpoint"
+	}
+	if o.resume && o.output == "" {
+		return errors.New("-resume needs an output file (-o)")
+	}
+
+	input := "-"
+	if len(e.cfg.inputs) == 1 {
+		input = e.cfg.inputs[0]
+	}
+	var in io.Reader = e.stdin
+	if input != "-" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	cp := checkpoint{Input: input, Output: o.output}
+	if o.resume {
+		prev, err := readCheckpoint(o.checkpoint)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if err == nil {
+			if prev.Input != input || prev.Output != o.output {
+				return fmt.Errorf("checkpoint %s is for input %s and output %s", o.checkpoint, prev.Input, prev.Output)
+			}
+			cp = prev
+		}
+	}
+
+	var out io.Writer = e.stdout
+	var outFile *os.File
+	if o.output != "" {
+		f, err := os.OpenFile(o.output, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		// drop records written after the checkpoint; they will be redone
+		if err := f.Truncate(cp.Offset); err != nil {
+			return err
+		}
+		if _, err := f.Seek(cp.Offset, io.SeekStart); err != nil {
+			return err
+		}
+		out, outFile = f, f
+	}
+	if cp.Line > 0 {
+		fmt.Fprintf(e.stderr, "resuming after line %d (%d records done)\n", cp.Line, cp.Records)
+	}
+	return e.batch(in, out, outFile, o, cp)
+}
+
+func readCheckpoint(path string) (checkpoint, error) {
+	var cp checkpoint
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return cp, err
+	}
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return cp, fmt.Errorf("checkpoint %s: %w", path, err)
+	}
+	return cp, nil
+}
+
+// save writes the checkpoint atomically.
+func (cp checkpoint) save(path string) error {
+	b, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// batch runs the lines of in after cp.Line through the ingest queue and the pipeline and writes their
+// records to out in input order, checkpointing as it goes when outFile is set.
+func (e *env) batch(in io.Reader, out io.Writer, outFile *os.File, o batchOptions, cp checkpoint) error {
+	opts, err := e.cfg.pipelineOptions()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(e.ctx)
+	defer cancel()
+	p := pipeline.New(ingest.DefaultQueueSize, pipeline.DefaultStages(opts)...)
+	p.Start(ctx)
+	q := consume(ctx, p)
+	defer q.stop()
+
+	// sentences of this input that an interrupted run left in the -queue-dir log:
+	// lines already in the output, or redone from scratch, are settled; the rest are
+	// resubmitted and used in place of their lines
+	replayed := make(map[int]*pipeline.Future)
+	sentences, futures, err := e.replay(ctx, p, func(s ingest.Sentence) bool {
+		if s.DocumentID != cp.Input {
+			return false
+		}
+		if !o.resume || s.Index <= cp.Line {
+			ingest.Ack(s.ID)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	for i, s := range sentences {
+		replayed[s.Index] = futures[i]
+	}
+
+	pending := make(chan pendingLine, ingest.DefaultQueueSize)
+	readErr := make(chan error, 1)
+	skip := cp.Line // the loop below advances cp.Line while the reader runs
+	go func() {
+		var err error
+		defer func() {
+			readErr <- err
+			close(pending)
+		}()
+		sc := bufio.NewScanner(in)
+		sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for line := 1; sc.Scan(); line++ {
+			if line <= skip || len(bytes.TrimSpace(sc.Bytes())) == 0 {
+				continue
+			}
+			pl := pendingLine{line: line, id: strconv.Itoa(line)}
+			text, id, perr := parseBatchLine(sc.Bytes(), o)
+			if id != "" {
+				pl.id = id
+			}
+			if f, ok := replayed[line]; ok {
+				pl.future, perr = f, nil
+			} else if perr == nil {
+				var s ingest.Sentence
+				if s, perr = ingest.NewSentence(text); perr == nil {
+					// input and line identify the sentence when it is replayed
+					s.DocumentID, s.Index = cp.Input, line
+					if err = ingest.Submit(ctx, s); err != nil {
+						return
+					}
+					pl.queued = true
+				}
+			}
+			pl.err = perr
+			select {
+			case pending <- pl:
+			case <-ctx.Done():
+				return
+			}
+		}
+		err = sc.Err()
+	}()
+
+	w := bufio.NewWriter(out)
+	offset := cp.Offset
+	sinceCheckpoint := 0
+	flush := func() error {
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if outFile == nil || o.checkpoint == "" {
+			return nil
+		}
+		if err := outFile.Sync(); err != nil {
+			return err
+		}
+		sinceCheckpoint = 0
+		return cp.save(o.checkpoint)
+	}
+
+	start, lastReport, done := time.Now(), time.Now(), 0
+	report := func() {
+		rate := float64(done) / time.Since(start).Seconds()
+		fmt.Fprintf(e.stderr, "line %d: %d records (%d errors), %.1f records/s\n", cp.Line, cp.Records, cp.Errors, rate)
+	}
+
+	for pl := range pending {
+		rec := batchRecord{Line: pl.line, ID: pl.id}
+		err := pl.err
+		switch {
+		case err != nil:
+		case pl.queued:
+			rec.Result, err = q.next(ctx)
+		default:
+			rec.Result, err = pl.future.Wait(ctx)
+		}
+		if ctx.Err() != nil {
+			// interrupted: keep what is done so -resume picks up here
+			if ferr := flush(); ferr != nil {
+				return ferr
+			}
+			return fmt.Errorf("interrupted after line %d (rerun with -resume to continue): %w", cp.Line, ctx.Err())
+		}
+		if err != nil {
+			if o.onError == "stop" {
+				if ferr := flush(); ferr != nil {
+					return ferr
+				}
+				return fmt.Errorf("line %d: %w", pl.line, err)
+			}
+			rec.Result, rec.Error = nil, err.Error()
+			cp.Errors++
+		}
+		b, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		n, err := w.Write(append(b, '\n'))
+		if err != nil {
+			return err
+		}
+		offset += int64(n)
+		cp.Line, cp.Offset = pl.line, offset
+		cp.Records++
+		done++
+		if sinceCheckpoint++; sinceCheckpoint >= o.every {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if o.progress > 0 && time.Since(lastReport) >= o.progress {
+			report()
+			lastReport = time.Now()
+		}
+	}
+	if err := firstErr(<-readErr, ctx.Err()); err != nil {
+		if ferr := flush(); ferr != nil {
+			return ferr
+		}
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	if o.progress > 0 {
+		report()
+	}
+	return nil
+}
+
+// parseBatchLine returns the text and ID of an input line. JSON records give both;
+// plain text lines have no ID.
+func parseBatchLine(line []byte, o batchOptions) (text, id string, err error) {
+	isJSON := o.inputFormat == "jsonl" || (o.inputFormat == "auto" && bytes.HasPrefix(bytes.TrimSpace(line), []byte("{")))
+	if !isJSON {
+		return string(line), "", nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	var rec map[string]any
+	if err := dec.Decode(&rec); err != nil {
+		return "", "", fmt.Errorf("invalid JSON record: %w", err)
+	}
+	if v, ok := rec[o.idField]; ok && v != nil {
+		id = fmt.Sprint(v)
+	}
+	text, ok := rec[o.textField].(string)
+	if !ok || strings.TrimSpace(text) == "" {
+		return "", id, fmt.Errorf("record has no %q text field", o.textField)
+	}
+	return text, id, nil
+}
+
+func firstErr(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
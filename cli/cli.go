@@ -0,0 +1,468 @@
+// Package cli implements the japaneseparse command line. Each subcommand (tokenize,
+// furigana, lookup, kanji, analyze, batch, repl, subtitles, serve) takes its input from
+// arguments, -i files or stdin and writes pretty, json, jsonl or tsv output.
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"japaneseparse/cache"
+	"japaneseparse/dictionary"
+	"japaneseparse/furigana"
+	"japaneseparse/ingest"
+	"japaneseparse/kanji"
+	"japaneseparse/pipeline"
+	"japaneseparse/tokenize"
+)
+
+// command is one subcommand.
+type command struct {
+	name    string
+	summary string
+	run     func(e *env, args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"tokenize", "split text into tokens", runTokenize},
+		{"furigana", "annotate text with furigana", runFurigana},
+		{"lookup", "look up every word in the dictionary", runLookup},
+		{"kanji", "show readings, grade and JLPT level of kanji", runKanji},
+		{"analyze", "run the full analysis", runAnalyze},
+		{"batch", "process one record per input line into JSONL", runBatch},
+		{"repl", "analyze sentences interactively", runRepl},
+		{"subtitles", "write furigana, glossed and vocabulary files for subtitles", runSubtitles},
+		{"serve", "start the HTTP API", runServe},
+	}
+}
+
+// errUsage reports bad arguments; the flag package has already printed the details.
+var errUsage = errors.New("usage error")
+
+// env is what a command runs with.
+type env struct {
+	ctx    context.Context
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	cfg    config
+	// cleanup runs when the command returns, most recent first
+	cleanup []func()
+}
+
+// Run executes a command line (without the program name) and returns the exit code:
+// 0 on success, 1 on failure and 2 on bad usage.
+func Run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stderr)
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+		e := &env{ctx: ctx, stdin: stdin, stdout: stdout, stderr: stderr}
+		err := c.run(e, args[1:])
+		for i := len(e.cleanup) - 1; i >= 0; i-- {
+			e.cleanup[i]()
+		}
+		if err != nil {
+			if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+				return 2
+			}
+			fmt.Fprintf(stderr, "%s: %v\n", c.name, err)
+			return 1
+		}
+		return 0
+	}
+	fmt.Fprintf(stderr, "unknown command %q\n\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: japaneseparse <command> [flags] [text...]")
+	fmt.Fprintln(w, "\nText comes from the arguments, from -i files (EPUB, HTML, text) or from stdin.")
+	fmt.Fprintln(w, "\ncommands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w, "\nRun 'japaneseparse <command> -h' for the flags of a command.")
+}
+
+// listFlag collects a flag given several times.
+type listFlag []string
+
+func (l *listFlag) String() string     { return strings.Join(*l, ",") }
+func (l *listFlag) Set(v string) error { *l = append(*l, v); return nil }
+
+// config holds the flags shared by every command.
+type config struct {
+	jmdict, enamdict, kanjidic string
+	mode                       string
+	format                     string
+	encoding                   string
+	verbose, debug             bool
+	inputs                     listFlag
+	grade, jlpt                int
+	known                      string
+	workers                    int
+	logs                       string
+	cacheDir                   string
+	contentIDs                 bool
+	queueDir                   string
+	aligner, overrides         string
+}
+
+// newFlagSet returns a flag set for a command with the shared flags registered into
+// e.cfg. defaultFormat is the -format default.
+func (e *env) newFlagSet(name, defaultFormat string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	c := &e.cfg
+	fs.StringVar(&c.jmdict, "jmdict", "dict/JMdict_e", "path to the JMdict XML file")
+	fs.StringVar(&c.enamdict, "enamdict", "dict/enamdict", "path to the JMnedict (ENAMDICT) XML file")
+	fs.StringVar(&c.kanjidic, "kanjidic", "dict/kanjidic2.xml", "path to kanjidic2.xml")
+	fs.StringVar(&c.mode, "mode", "normal", "kagome tokenizer mode: normal, search or extended")
+	fs.StringVar(&c.format, "format", defaultFormat, "output format: pretty, json, jsonl, tsv or conllu")
+	fs.StringVar(&c.encoding, "encoding", "auto", "input encoding: auto, utf-8, utf-16le, utf-16be, shift_jis, cp932, euc-jp or iso-2022-jp")
+	fs.BoolVar(&c.verbose, "v", false, "print log output to stderr")
+	fs.BoolVar(&c.debug, "debug", false, "print dictionary debug information")
+	fs.Var(&c.inputs, "i", "input file, or - for stdin (repeatable)")
+	fs.IntVar(&c.grade, "grade", 0, "reader knows kanji up to this school grade (1-6, 8 for all Joyo)")
+	fs.IntVar(&c.jlpt, "jlpt", 0, "reader knows kanji down to this JLPT level (4 easiest, 1 hardest)")
+	fs.StringVar(&c.known, "known", "", "kanji the reader knows")
+	fs.IntVar(&c.workers, "workers", 1, "workers per pipeline stage")
+	fs.StringVar(&c.logs, "logs", "", "write per-sentence JSON logs to this directory")
+	fs.StringVar(&c.cacheDir, "cache", "", "cache analysis results in this directory")
+	fs.BoolVar(&c.contentIDs, "content-ids", false, "derive sentence IDs from their text")
+	fs.StringVar(&c.aligner, "aligner", "override,kanjidic,numeric,dictionary", "furigana strategies to try in order: override, kanjidic, numeric, dictionary")
+	fs.StringVar(&c.overrides, "overrides", "", "TSV of furigana overrides (surface, reading, segmentation such as 明=あ 日=した)")
+	fs.StringVar(&c.queueDir, "queue-dir", "", "keep a log of queued sentences in this directory and first finish those an interrupted run left")
+	return fs
+}
+
+// parse parses args, applies the shared flags (logging, tokenizer mode, encoding and
+// IDs) and loads the dictionaries.
+func (e *env) parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage // the flag package has printed the error and usage
+	}
+	c := &e.cfg
+	if c.verbose {
+		log.SetOutput(e.stderr)
+	} else {
+		// kanji lookups and stages log every step; keep the output readable
+		log.SetOutput(io.Discard)
+	}
+	if err := tokenize.SetMode(c.mode); err != nil {
+		return err
+	}
+	enc, err := ingest.ParseEncoding(c.encoding)
+	if err != nil {
+		return err
+	}
+	ingest.InputEncoding = enc
+	ingest.ContentIDs = c.contentIDs
+	if err := e.setAligner(); err != nil {
+		return err
+	}
+	if c.queueDir != "" {
+		if err := e.openQueue(c.queueDir); err != nil {
+			return err
+		}
+	}
+	e.loadDictionaries()
+	return nil
+}
+
+// setAligner loads -overrides and installs the -aligner chain until the command
+// returns.
+func (e *env) setAligner() error {
+	c := e.cfg
+	prev := furigana.UserOverrides
+	if c.overrides != "" {
+		o := furigana.NewOverrides()
+		if err := o.Load(c.overrides); err != nil {
+			return err
+		}
+		furigana.UserOverrides = o
+	}
+	a, err := furigana.NewChain(strings.Split(c.aligner, ",")...)
+	if err != nil {
+		furigana.UserOverrides = prev
+		return err
+	}
+	tokenize.SetAligner(a)
+	e.cleanup = append(e.cleanup, func() {
+		furigana.UserOverrides = prev
+		tokenize.SetAligner(nil)
+	})
+	return nil
+}
+
+// openQueue records queued sentences in <dir>/queue.log until the command returns.
+func (e *env) openQueue(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	b, err := ingest.OpenFileBackend(filepath.Join(dir, "queue.log"))
+	if err != nil {
+		return err
+	}
+	ingest.SetBackend(b)
+	e.cleanup = append(e.cleanup, func() {
+		ingest.SetBackend(nil)
+		if err := b.Close(); err != nil {
+			fmt.Fprintln(e.stderr, "warning: closing queue log:", err)
+		}
+	})
+	return nil
+}
+
+// replay resubmits the sentences an earlier run left pending in the -queue-dir log,
+// oldest first. keep selects the sentences to replay; nil keeps all of them. The
+// others stay pending.
+func (e *env) replay(ctx context.Context, p *pipeline.Pipeline, keep func(ingest.Sentence) bool) ([]ingest.Sentence, []*pipeline.Future, error) {
+	if e.cfg.queueDir == "" {
+		return nil, nil, nil
+	}
+	pending, err := ingest.Pending()
+	if err != nil {
+		return nil, nil, err
+	}
+	var sentences []ingest.Sentence
+	var futures []*pipeline.Future
+	for _, s := range pending {
+		if keep != nil && !keep(s) {
+			continue
+		}
+		f, err := p.Resubmit(ctx, s)
+		if err != nil {
+			return nil, nil, err
+		}
+		sentences, futures = append(sentences, s), append(futures, f)
+	}
+	if len(futures) > 0 {
+		fmt.Fprintf(e.stderr, "replaying %d sentences left unfinished by an earlier run\n", len(futures))
+	}
+	return sentences, futures, nil
+}
+
+// loadDictionaries loads JMdict and Kanjidic2. Missing dictionaries are reported but
+// not fatal: tokenizing and furigana still work from kagome's readings.
+func (e *env) loadDictionaries() {
+	c := e.cfg
+	if err := dictionary.InitDictionaries(c.jmdict, c.enamdict); err != nil {
+		fmt.Fprintln(e.stderr, "warning: failed to load dictionaries:", err)
+	}
+	if _, err := os.Stat(c.kanjidic); err != nil {
+		fmt.Fprintln(e.stderr, "warning: kanjidic2 not found:", err)
+	} else if err := kanji.InitKanjidic2(c.kanjidic); err != nil {
+		fmt.Fprintln(e.stderr, "warning: failed to load Kanjidic2:", err)
+	}
+	if c.debug {
+		fmt.Fprintf(e.stderr, "Kanjidic2 loaded: %d kanji entries\n", kanji.Count())
+		fmt.Fprintf(e.stderr, "dictionary version: %s\n", dictionary.Version())
+		dictionary.DebugGlossaryFields()
+	}
+}
+
+// profile builds the reader profile from -grade, -jlpt and -known; nil if none is set.
+func (c config) profile() *kanji.ReaderProfile {
+	if c.grade == 0 && c.jlpt == 0 && c.known == "" {
+		return nil
+	}
+	p := kanji.NewKnownKanjiProfile(c.known)
+	p.Grade, p.JLPT = c.grade, c.jlpt
+	return p
+}
+
+// pipelineOptions returns the pipeline options for the shared flags.
+func (c config) pipelineOptions() (pipeline.Options, error) {
+	opts := pipeline.Options{Profile: c.profile(), Workers: make(map[string]int)}
+	if c.cacheDir != "" {
+		ch, err := cache.New(0, c.cacheDir)
+		if err != nil {
+			return opts, err
+		}
+		opts.Cache = ch
+	}
+	for _, st := range pipeline.DefaultStages(opts) {
+		opts.Workers[st.Name] = c.workers
+	}
+	return opts, nil
+}
+
+// documents returns the input: the arguments as one document if there are any, then
+// every -i file; stdin if there is neither.
+func (e *env) documents(args []string) ([]ingest.Document, error) {
+	var docs []ingest.Document
+	if len(args) > 0 {
+		doc, err := ingest.NewDocument(strings.Join(args, "\n"), ingest.Metadata{Source: "args"})
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	inputs := e.cfg.inputs
+	if len(args) == 0 && len(inputs) == 0 {
+		inputs = listFlag{"-"}
+	}
+	for _, name := range inputs {
+		var doc ingest.Document
+		var err error
+		if name == "-" {
+			doc, err = ingest.DocumentFromText(e.stdin, ingest.Metadata{Source: "stdin"})
+		} else {
+			doc, err = ingest.DocumentFromFile(name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// queueRunner drains the ingest queue into a started pipeline with Consume and hands
+// the results back in queue order.
+type queueRunner struct {
+	p       *pipeline.Pipeline
+	results chan queued
+	cancel  context.CancelFunc
+	done    chan error
+}
+
+// queued is the outcome of one sentence taken from the ingest queue.
+type queued struct {
+	r   *pipeline.Result
+	err error
+}
+
+// consume starts consuming the ingest queue into p, which must be started.
+func consume(ctx context.Context, p *pipeline.Pipeline) *queueRunner {
+	ctx, cancel := context.WithCancel(ctx)
+	q := &queueRunner{p: p, results: make(chan queued, ingest.DefaultQueueSize), cancel: cancel, done: make(chan error, 1)}
+	go func() {
+		q.done <- p.Consume(ctx, func(r *pipeline.Result, err error) {
+			select {
+			case q.results <- queued{r, err}:
+			case <-ctx.Done():
+			}
+		})
+	}()
+	return q
+}
+
+// next waits for the result of the next sentence taken from the queue.
+func (q *queueRunner) next(ctx context.Context) (*pipeline.Result, error) {
+	select {
+	case res := <-q.results:
+		return res.r, res.err
+	case err := <-q.done:
+		q.done <- err
+		if err == nil {
+			err = pipeline.ErrClosed
+		}
+		return nil, err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// stop ends consumption and closes the pipeline. Sentences still queued after an
+// interruption are dropped from the queue so a later command does not receive them;
+// they stay pending in the backend.
+func (q *queueRunner) stop() {
+	q.cancel()
+	<-q.done
+	q.p.Close()
+	for {
+		select {
+		case <-ingest.Queue():
+		default:
+			return
+		}
+	}
+}
+
+// process runs every sentence of docs through the default stages up to and including
+// last and calls fn with the results in input order. The sentences go through the
+// ingest queue, which records them in the -queue-dir log until they are done. A
+// sentence that fails is reported on stderr and skipped.
+func (e *env) process(docs []ingest.Document, last string, fn func(doc ingest.Document, r *pipeline.Result) error) error {
+	opts, err := e.cfg.pipelineOptions()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(e.ctx)
+	defer cancel()
+	p := pipeline.New(ingest.DefaultQueueSize, pipeline.StagesUntil(opts, last)...)
+	p.Start(ctx)
+	q := consume(ctx, p)
+	defer q.stop()
+
+	replayed, futures, err := e.replay(ctx, p, nil)
+	if err != nil {
+		return err
+	}
+	for i, f := range futures {
+		r, err := f.Wait(ctx)
+		if err != nil {
+			fmt.Fprintf(e.stderr, "sentence %s failed: %v\n", replayed[i].ID, err)
+		} else if err := fn(ingest.Document{ID: replayed[i].DocumentID}, r); err != nil {
+			return err
+		}
+	}
+
+	submitErr := make(chan error, 1)
+	go func() {
+		for _, doc := range docs {
+			if err := ingest.SubmitAll(ctx, doc.Sentences()); err != nil {
+				submitErr <- err
+				cancel() // wake the loop below, which waits for this sentence
+				return
+			}
+		}
+		submitErr <- nil
+	}()
+	for _, doc := range docs {
+		for _, s := range doc.Sentences() {
+			r, err := q.next(ctx)
+			if err != nil && r == nil {
+				// the sentence never came back: submission stopped or the run ended
+				select {
+				case serr := <-submitErr:
+					if serr != nil {
+						return serr
+					}
+				default:
+				}
+				return err
+			}
+			if err != nil {
+				fmt.Fprintf(e.stderr, "sentence %s failed: %v\n", s.ID, err)
+			} else if err := fn(doc, r); err != nil {
+				return err
+			}
+		}
+	}
+	return <-submitErr
+}
@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"japaneseparse/ingest"
+)
+
+func run(t *testing.T, stdin string, args ...string) (string, int) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := Run(context.Background(), args, strings.NewReader(stdin), &stdout, &stderr)
+	if code != 0 {
+		t.Logf("stderr: %s", stderr.String())
+	}
+	return stdout.String(), code
+}
+
+func TestCommands(t *testing.T) {
+	out, code := run(t, "", "tokenize", "-format", "tsv", "雨が降った。")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if code != 0 || len(lines) != 6 || !strings.HasPrefix(lines[0], "sentence_id\tindex\tsurface") {
+		t.Errorf("tokenize tsv: code %d\n%s", code, out)
+	}
+
+	out, code = run(t, "雨が降った", "furigana", "-format", "jsonl")
+	var rec furiganaRecord
+	if err := json.Unmarshal([]byte(out), &rec); err != nil || code != 0 || rec.Furigana != "[雨|あめ]が[降|ふ]った" {
+		t.Errorf("furigana from stdin: code %d, %q (%v)", code, out, err)
+	}
+
+	out, code = run(t, "今日は\n\n明日\n", "batch")
+	lines = strings.Split(strings.TrimSpace(out), "\n")
+	var first batchRecord
+	json.Unmarshal([]byte(lines[0]), &first)
+	if code != 0 || len(lines) != 2 || first.Line != 1 || first.Result == nil || first.Result.Analysis == nil {
+		t.Errorf("batch: code %d\n%s", code, out)
+	}
+
+	overrides := filepath.Join(t.TempDir(), "overrides.tsv")
+	os.WriteFile(overrides, []byte("# surface\treading\tsegmentation\n明日\tあした\t明=あ 日=した\n"), 0644)
+	out, code = run(t, "", "furigana", "-format", "jsonl", "-overrides", overrides, "明日")
+	if err := json.Unmarshal([]byte(out), &rec); err != nil || code != 0 || rec.Furigana != "[明|あ][日|した]" {
+		t.Errorf("furigana with overrides: code %d, %q (%v)", code, out, err)
+	}
+	out, code = run(t, "", "furigana", "-format", "jsonl", "-aligner", "dictionary", "明日")
+	if err := json.Unmarshal([]byte(out), &rec); err != nil || code != 0 || rec.Furigana != "[明日|あした]" {
+		t.Errorf("furigana with the dictionary aligner: code %d, %q (%v)", code, out, err)
+	}
+	if _, code := run(t, "", "furigana", "-aligner", "guess", "明日"); code != 1 {
+		t.Errorf("unknown aligner: code %d, want 1", code)
+	}
+
+	if _, code := run(t, "", "tokenize", "-format", "xml", "雨"); code != 1 {
+		t.Errorf("bad format: code %d, want 1", code)
+	}
+	if _, code := run(t, "", "nonsense"); code != 2 {
+		t.Errorf("unknown command: code %d, want 2", code)
+	}
+}
+
+func TestRepl(t *testing.T) {
+	script := "雨が降った。\n:furigana\n:lookup 0\n:lookup 9\n!1\n:quit\n"
+	out, code := run(t, script, "repl", "-history", "")
+	if code != 0 {
+		t.Fatalf("code %d\n%s", code, out)
+	}
+	for _, want := range []string{"[雨|あめ]が[降|ふ]った。", "lemma", "token 9 out of range"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	// !1 repeats the sentence in the furigana view
+	if strings.Count(out, "[雨|あめ]が[降|ふ]った。") != 2 {
+		t.Errorf("history recall did not rerun the sentence:\n%s", out)
+	}
+}
+
+// The batch, text and REPL commands feed sentences through the ingest queue.
+func TestCommandsUseIngestQueue(t *testing.T) {
+	cases := []struct {
+		stdin string
+		args  []string
+		want  uint64
+	}{
+		{"", []string{"tokenize", "-format", "jsonl", "雨が降った。晴れた。"}, 2},
+		{"雨が降った。\n\n晴れた。\n", []string{"batch"}, 2},
+		{"雨が降った。\n:quit\n", []string{"repl", "-history", ""}, 1},
+	}
+	for _, c := range cases {
+		before := ingest.Stats().Submitted
+		if out, code := run(t, c.stdin, c.args...); code != 0 {
+			t.Fatalf("%s: code %d\n%s", c.args[0], code, out)
+		}
+		st := ingest.Stats()
+		if got := st.Submitted - before; got != c.want || st.Queued != 0 {
+			t.Errorf("%s: %d sentences through the queue, %d left; want %d, 0", c.args[0], got, st.Queued, c.want)
+		}
+	}
+}
+
+func TestBatchResume(t *testing.T) {
+	dir := t.TempDir()
+	in, out := filepath.Join(dir, "in.jsonl"), filepath.Join(dir, "out.jsonl")
+	os.WriteFile(in, []byte(`{"id":"a","text":"雨が降った。"}`+"\n"+`{"id":7,"text":""}`+"\n"), 0644)
+	if _, code := run(t, "", "batch", "-progress", "0", "-i", in, "-o", out); code != 0 {
+		t.Fatalf("first run: code %d", code)
+	}
+
+	// more input arrives, and a crash left half a record after the checkpoint
+	f, _ := os.OpenFile(in, os.O_WRONLY|os.O_APPEND, 0)
+	f.WriteString("明日は晴れる。\n")
+	f.Close()
+	f, _ = os.OpenFile(out, os.O_WRONLY|os.O_APPEND, 0)
+	f.WriteString(`{"line":3,"id":"3","res`)
+	f.Close()
+	if _, code := run(t, "", "batch", "-progress", "0", "-resume", "-i", in, "-o", out); code != 0 {
+		t.Fatalf("resumed run: code %d", code)
+	}
+
+	b, _ := os.ReadFile(out)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("output has %d records, want 3:\n%s", len(lines), b)
+	}
+	var recs [3]batchRecord
+	for i, l := range lines {
+		if err := json.Unmarshal([]byte(l), &recs[i]); err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+	}
+	if recs[0].ID != "a" || recs[0].Result == nil || recs[1].ID != "7" || recs[1].Error == "" || recs[2].Line != 3 || recs[2].Result == nil {
+		t.Errorf("unexpected records:\n%s", b)
+	}
+	cp, err := readCheckpoint(out + ".checkpoint")
+	if err != nil || cp.Line != 3 || cp.Records != 3 || cp.Errors != 1 || cp.Offset != int64(len(b)) {
+		t.Errorf("checkpoint = %+v, %v", cp, err)
+	}
+}
+
+func TestQueueReplay(t *testing.T) {
+	dir := t.TempDir()
+	// a sentence queued by a run that died before finishing it
+	b, err := ingest.OpenFileBackend(filepath.Join(dir, "queue.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _ := ingest.NewSentence("雨が降った。")
+	b.Append(s)
+	b.Close()
+
+	for i, want := range []int{2, 1} {
+		out, code := run(t, "", "tokenize", "-queue-dir", dir, "-format", "jsonl", "晴れ")
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		if code != 0 || len(lines) != want {
+			t.Fatalf("run %d: code %d, %d records, want %d\n%s", i, code, len(lines), want, out)
+		}
+		if i == 0 && !strings.Contains(lines[0], "雨が降った") {
+			t.Errorf("replayed sentence is not first:\n%s", out)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"japaneseparse/analyze"
+	"japaneseparse/conllu"
+	"japaneseparse/ingest"
+	"japaneseparse/kanji"
+	"japaneseparse/logger"
+	"japaneseparse/model"
+	"japaneseparse/pipeline"
+	"japaneseparse/server"
+	"japaneseparse/subtitle"
+)
+
+var tokenHeader = []string{"sentence_id", "index", "surface", "lemma", "reading", "pos", "inflection_type", "inflection_form", "start", "end"}
+
+func tokenRows(id string, toks []model.Token) [][]string {
+	rows := make([][]string, len(toks))
+	for i, t := range toks {
+		rows[i] = []string{id, strconv.Itoa(i), t.Text, t.Lemma, t.Reading, t.POS, t.InflectionType, t.InflectionForm, strconv.Itoa(t.Start), strconv.Itoa(t.End)}
+	}
+	return rows
+}
+
+// tokenTable is the pretty form of a token list: index, surface, reading, lemma, POS.
+func tokenTable(toks []model.Token) string {
+	rows := make([][]string, len(toks))
+	for i, t := range toks {
+		rows[i] = []string{strconv.Itoa(i), t.Text, t.Reading, t.Lemma, t.POS}
+	}
+	return table(rows)
+}
+
+// conlluSentence is the conllu form of a result. The analysis indexes the merged
+// tokens, so pass it only with r.Merged.
+func conlluSentence(r *pipeline.Result, toks []model.Token, a *analyze.Analysis) *conllu.Sentence {
+	s := r.Sentence
+	return &conllu.Sentence{ID: s.ID, Text: s.Text, Raw: s.Raw, DocumentID: s.DocumentID, Paragraph: s.Paragraph, Tokens: toks, Analysis: a}
+}
+
+func runTokenize(e *env, args []string) error {
+	fs := e.newFlagSet("tokenize", FormatPretty)
+	merged := fs.Bool("merged", false, "merge verbs with their auxiliaries and numbers with their counters")
+	if err := e.parse(fs, args); err != nil {
+		return err
+	}
+	last := "tokenize"
+	if *merged {
+		last = "merge"
+	}
+	return e.eachResult(fs.Args(), last, func(out *output, doc ingest.Document, r *pipeline.Result) error {
+		toks := r.Tokens
+		if *merged {
+			toks = r.Merged
+		}
+		return out.write(record{
+			value:  r,
+			header: tokenHeader,
+			rows:   tokenRows(r.Sentence.ID, toks),
+			pretty: "# " + r.Sentence.Text + "\n" + tokenTable(toks) + "\n",
+			conllu: conlluSentence(r, toks, nil),
+		})
+	})
+}
+
+// eachResult reads the documents named by args and the shared flags, runs them up to
+// stage last and hands every result to fn together with the output.
+func (e *env) eachResult(args []string, last string, fn func(out *output, doc ingest.Document, r *pipeline.Result) error) error {
+	out, err := newOutput(e.cfg.format, e.stdout)
+	if err != nil {
+		return err
+	}
+	docs, err := e.documents(args)
+	if err != nil {
+		return err
+	}
+	err = e.process(docs, last, func(doc ingest.Document, r *pipeline.Result) error {
+		return fn(out, doc, r)
+	})
+	if err != nil {
+		return err
+	}
+	return out.close()
+}
+
+// furiganaRecord is the json form of a furigana line.
+type furiganaRecord struct {
+	SentenceID string `json:"sentence_id"`
+	Text       string `json:"text"`
+	Furigana   string `json:"furigana"`
+}
+
+func runFurigana(e *env, args []string) error {
+	fs := e.newFlagSet("furigana", FormatPretty)
+	ruby := fs.String("ruby", string(subtitle.RubyBrackets), "furigana notation: brackets, html, aozora or ass")
+	if err := e.parse(fs, args); err != nil {
+		return err
+	}
+	style, err := subtitle.ParseRubyStyle(*ruby)
+	if err != nil {
+		return err
+	}
+	profile := e.cfg.profile()
+	return e.eachResult(fs.Args(), "furigana", func(out *output, doc ingest.Document, r *pipeline.Result) error {
+		rec := furiganaRecord{SentenceID: r.Sentence.ID, Text: r.Sentence.Text, Furigana: subtitle.Ruby(r.Merged, style, profile)}
+		return out.write(record{
+			value:  rec,
+			header: []string{"sentence_id", "text", "furigana"},
+			rows:   [][]string{{rec.SentenceID, rec.Text, rec.Furigana}},
+			pretty: rec.Furigana + "\n",
+		})
+	})
+}
+
+func runLookup(e *env, args []string) error {
+	fs := e.newFlagSet("lookup", FormatPretty)
+	if err := e.parse(fs, args); err != nil {
+		return err
+	}
+	return e.eachResult(fs.Args(), "dictionary", func(out *output, doc ingest.Document, r *pipeline.Result) error {
+		var rows, pretty [][]string
+		for i, t := range r.Merged {
+			d := t.DictionaryEntry
+			glosses := strings.Join(d.Glosses, "; ")
+			rows = append(rows, []string{r.Sentence.ID, strconv.Itoa(i), t.Text, t.Lemma, t.Reading, d.Source, glosses})
+			pretty = append(pretty, []string{strconv.Itoa(i), t.Text, t.Reading, glosses})
+		}
+		return out.write(record{
+			value:  r,
+			header: []string{"sentence_id", "index", "surface", "lemma", "reading", "source", "glosses"},
+			rows:   rows,
+			pretty: "# " + r.Sentence.Text + "\n" + table(pretty) + "\n",
+		})
+	})
+}
+
+func runKanji(e *env, args []string) error {
+	fs := e.newFlagSet("kanji", FormatPretty)
+	if err := e.parse(fs, args); err != nil {
+		return err
+	}
+	out, err := newOutput(e.cfg.format, e.stdout)
+	if err != nil {
+		return err
+	}
+	docs, err := e.documents(fs.Args())
+	if err != nil {
+		return err
+	}
+	seen := make(map[rune]bool)
+	for _, doc := range docs {
+		for _, c := range doc.Text {
+			if !kanji.IsKanji(c) || seen[c] {
+				continue
+			}
+			seen[c] = true
+			info := kanji.GetKanjiInfo(c)
+			readings := strings.Join(info.Readings, " ")
+			err := out.write(record{
+				value:  info,
+				header: []string{"kanji", "readings", "grade", "jlpt"},
+				rows:   [][]string{{info.Kanji, readings, strconv.Itoa(info.Grade), strconv.Itoa(info.JLPT)}},
+				pretty: table([][]string{{info.Kanji, fmt.Sprintf("grade %d", info.Grade), fmt.Sprintf("JLPT %d", info.JLPT), readings}}),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if len(seen) == 0 {
+		return errors.New("no kanji in input")
+	}
+	return out.close()
+}
+
+func runAnalyze(e *env, args []string) error {
+	fs := e.newFlagSet("analyze", FormatPretty)
+	if err := e.parse(fs, args); err != nil {
+		return err
+	}
+	logs := e.cfg.logs
+	if logs != "" {
+		if err := os.MkdirAll(logs, 0755); err != nil {
+			return err
+		}
+		if err := logger.InitLogs(logs); err != nil {
+			return err
+		}
+	}
+	byDoc := make(map[string][]analyze.Analysis)
+	var docs []ingest.Document
+	err := e.eachResult(fs.Args(), "analyze", func(out *output, doc ingest.Document, r *pipeline.Result) error {
+		if len(docs) == 0 || docs[len(docs)-1].ID != doc.ID {
+			docs = append(docs, doc)
+		}
+		byDoc[doc.ID] = append(byDoc[doc.ID], *r.Analysis)
+		if logs != "" {
+			writeLogs(e, logs, r)
+		}
+		analysis, _ := json.MarshalIndent(r.Analysis, "", "  ")
+		return out.write(record{
+			value:  r,
+			header: tokenHeader,
+			rows:   tokenRows(r.Sentence.ID, r.Merged),
+			pretty: "# " + r.Sentence.Text + "\n" + tokenTable(r.Merged) + string(analysis) + "\n\n",
+			conllu: conlluSentence(r, r.Merged, r.Analysis),
+		})
+	})
+	if err != nil || logs == "" {
+		return err
+	}
+	// write per-document results to <logs>/<doc id>_document.json
+	for _, doc := range docs {
+		if err := logger.LogJSON(logs, doc.ID+"_document", analyze.GroupByDocument(doc, byDoc[doc.ID])); err != nil {
+			fmt.Fprintln(e.stderr, "failed to write document log:", err)
+		}
+	}
+	return nil
+}
+
+// writeLogs writes a sentence's tokens, dictionary entries and analysis to dir.
+func writeLogs(e *env, dir string, r *pipeline.Result) {
+	id := r.Sentence.ID
+	files := map[string]any{
+		id + "_tokens":          map[string]any{"original_tokens": r.Tokens, "merged_tokens": r.Merged},
+		id + "_enriched_tokens": r.Merged,
+		id + "_dict":            r.Entries,
+		id + "_merged":          map[string]any{"sentence_id": id, "token_count": len(r.Merged), "tokens": r.Merged, "analysis": r.Analysis},
+		id + "_analysis":        r.Analysis,
+	}
+	for name, v := range files {
+		if err := logger.LogJSON(dir, name, v); err != nil {
+			fmt.Fprintf(e.stderr, "failed to write log %s: %v\n", name, err)
+		}
+	}
+}
+
+func runSubtitles(e *env, args []string) error {
+	fs := e.newFlagSet("subtitles", FormatPretty)
+	ruby := fs.String("ruby", "", "furigana notation (default: the format's own: ass, html for WebVTT, aozora for SRT)")
+	outEnc := fs.String("output-encoding", "auto", "encoding of the written subtitles (auto: the encoding of each input file)")
+	if err := e.parse(fs, args); err != nil {
+		return err
+	}
+	style, err := subtitle.ParseRubyStyle(*ruby)
+	if err != nil {
+		return err
+	}
+	enc, err := ingest.ParseEncoding(*outEnc)
+	if err != nil {
+		return err
+	}
+	paths := append(fs.Args(), e.cfg.inputs...)
+	if len(paths) == 0 {
+		fmt.Fprintln(e.stderr, "subtitles: no subtitle files given")
+		return errUsage
+	}
+	for _, path := range paths {
+		if err := e.processSubtitles(path, style, enc); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		fmt.Fprintln(e.stdout, "annotated", path)
+	}
+	return nil
+}
+
+// processSubtitles writes three files next to a subtitle file: <name>.furigana<ext>
+// with furigana (in the format's ruby style unless style is set), <name>.glossed<ext>
+// with glosses for unknown words, and <name>.vocab.json with the words of every cue.
+// All three are written in enc, or in the encoding of the input if enc is
+// EncodingAuto.
+func (e *env) processSubtitles(path string, style subtitle.RubyStyle, enc ingest.Encoding) error {
+	f, err := subtitle.ParseFile(path)
+	if err != nil {
+		return err
+	}
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	if style == subtitle.RubyNone {
+		style = subtitle.DefaultRuby(f.Format)
+	}
+	if enc == ingest.EncodingAuto {
+		enc = f.Encoding
+	}
+	profile := e.cfg.profile()
+
+	files, vocab, err := subtitle.Annotate(e.ctx, f,
+		subtitle.Options{Ruby: style, Profile: profile},
+		subtitle.Options{Glosses: true, Profile: profile})
+	if err != nil {
+		return err
+	}
+	if err := e.writeSubtitles(files[0], base+".furigana"+filepath.Ext(path), enc); err != nil {
+		return err
+	}
+	if err := e.writeSubtitles(files[1], base+".glossed"+filepath.Ext(path), enc); err != nil {
+		return err
+	}
+	var sb strings.Builder
+	if err := subtitle.WriteVocab(&sb, vocab); err != nil {
+		return err
+	}
+	b, bad, err := ingest.Encode(sb.String(), enc)
+	if err != nil {
+		return err
+	}
+	e.warnUnencodable(base+".vocab.json", bad, enc)
+	return os.WriteFile(base+".vocab.json", b, 0644)
+}
+
+// writeSubtitles writes f to path in enc, warning about every character enc cannot
+// represent.
+func (e *env) writeSubtitles(f *subtitle.File, path string, enc ingest.Encoding) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	bad, err := f.WriteEncoded(out, enc)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	e.warnUnencodable(path, bad, enc)
+	return err
+}
+
+func (e *env) warnUnencodable(path string, bad []ingest.Unencodable, enc ingest.Encoding) {
+	for _, u := range bad {
+		fmt.Fprintf(e.stderr, "warning: %s:%s is not representable in %s; wrote ?\n", path, u, enc)
+	}
+}
+
+func runServe(e *env, args []string) error {
+	fs := e.newFlagSet("serve", FormatJSON)
+	addr := fs.String("addr", ":8080", "listen address")
+	timeout := fs.Duration("timeout", server.DefaultTimeout, "per-request timeout")
+	maxBody := fs.Int64("max-body", server.DefaultMaxBodyBytes, "maximum request body size in bytes")
+	if err := e.parse(fs, args); err != nil {
+		return err
+	}
+	fmt.Fprintln(e.stderr, "listening on", *addr)
+	return server.New(server.Options{Addr: *addr, Timeout: *timeout, MaxBodyBytes: *maxBody}).ListenAndServe(e.ctx)
+}
@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/width"
+
+	"japaneseparse/conllu"
+)
+
+// Output formats accepted by -format.
+const (
+	FormatPretty = "pretty"
+	FormatJSON   = "json"
+	FormatJSONL  = "jsonl"
+	FormatTSV    = "tsv"
+	FormatCoNLLU = "conllu"
+)
+
+// record is one unit of command output in every format.
+type record struct {
+	value  any              // json and jsonl
+	header []string         // tsv column names, written once before the first row
+	rows   [][]string       // tsv
+	pretty string           // pretty
+	conllu *conllu.Sentence // conllu, for commands that produce tokens
+}
+
+// output writes records in the selected format. JSON collects every record into one
+// array written by close; the other formats stream.
+type output struct {
+	format string
+	w      io.Writer
+	values []any
+	header bool
+	conllu *conllu.Writer
+}
+
+func newOutput(format string, w io.Writer) (*output, error) {
+	switch format {
+	case FormatPretty, FormatJSON, FormatJSONL, FormatTSV:
+		return &output{format: format, w: w}, nil
+	case FormatCoNLLU:
+		return &output{format: format, w: w, conllu: conllu.NewWriter(w)}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q (want pretty, json, jsonl, tsv or conllu)", format)
+}
+
+func (o *output) write(r record) error {
+	switch o.format {
+	case FormatJSON:
+		o.values = append(o.values, r.value)
+	case FormatJSONL:
+		b, err := json.Marshal(r.value)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(o.w, "%s\n", b)
+		return err
+	case FormatTSV:
+		if !o.header && len(r.header) > 0 {
+			o.header = true
+			if err := writeTSVRow(o.w, r.header); err != nil {
+				return err
+			}
+		}
+		for _, row := range r.rows {
+			if err := writeTSVRow(o.w, row); err != nil {
+				return err
+			}
+		}
+	case FormatPretty:
+		_, err := io.WriteString(o.w, r.pretty)
+		return err
+	case FormatCoNLLU:
+		if r.conllu == nil {
+			return fmt.Errorf("%s output is not available for this command", FormatCoNLLU)
+		}
+		return o.conllu.Write(*r.conllu)
+	}
+	return nil
+}
+
+func (o *output) close() error {
+	if o.format != FormatJSON {
+		return nil
+	}
+	if o.values == nil {
+		o.values = []any{}
+	}
+	b, err := json.MarshalIndent(o.values, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(o.w, "%s\n", b)
+	return err
+}
+
+// tsvEscaper keeps every field on one line and in one column.
+var tsvEscaper = strings.NewReplacer("\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func writeTSVRow(w io.Writer, fields []string) error {
+	escaped := make([]string, len(fields))
+	for i, f := range fields {
+		escaped[i] = tsvEscaper.Replace(f)
+	}
+	_, err := io.WriteString(w, strings.Join(escaped, "\t")+"\n")
+	return err
+}
+
+// table lays rows out in aligned columns for pretty output, counting wide (CJK)
+// characters as two columns.
+func table(rows [][]string) string {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], displayWidth(cell))
+		}
+	}
+	var sb strings.Builder
+	for _, row := range rows {
+		var line strings.Builder
+		for i, cell := range row {
+			line.WriteString(cell)
+			if i < len(row)-1 {
+				line.WriteString(strings.Repeat(" ", widths[i]-displayWidth(cell)+2))
+			}
+		}
+		sb.WriteString(strings.TrimRight(line.String(), " "))
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		switch width.LookupRune(r).Kind() {
+		case width.EastAsianWide, width.EastAsianFullwidth:
+			n += 2
+		default:
+			n++
+		}
+	}
+	return n
+}
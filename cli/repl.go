@@ -0,0 +1,342 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"japaneseparse/ingest"
+	"japaneseparse/kanji"
+	"japaneseparse/model"
+	"japaneseparse/pipeline"
+	"japaneseparse/subtitle"
+	"japaneseparse/tokenize"
+)
+
+// replViews are the ways the REPL can show a sentence.
+var replViews = []string{"tokens", "merged", "furigana", "glosses", "clauses", "analysis"}
+
+const replHelp = `Type a sentence to analyze it, or a command:
+  :tokens :merged :furigana :glosses :clauses :analysis   switch the view
+  :lookup N      show token N of the last sentence with its dictionary entry
+  :kanji [text]  show kanji info for text, or for the last sentence
+  :mode [name]   show or set the tokenizer mode (normal, search, extended)
+  :ruby [style]  show or set the furigana notation (brackets, html, aozora, ass)
+  :history       list earlier input; !N repeats entry N and !! the last one
+  :help          show this help
+  :quit          leave
+`
+
+// repl is the state of an interactive session. The dictionaries stay loaded between
+// lines, so only the first sentence pays the startup cost.
+type repl struct {
+	e       *env
+	w       io.Writer
+	view    string
+	ruby    subtitle.RubyStyle
+	run     *queueRunner // consumes the ingest queue into the current pipeline
+	last    *pipeline.Result
+	history []string
+	histLog io.Writer // history file, if any
+}
+
+func runRepl(e *env, args []string) error {
+	fs := e.newFlagSet("repl", FormatPretty)
+	view := fs.String("view", "merged", "initial view: "+strings.Join(replViews, ", "))
+	histPath := fs.String("history", defaultHistoryPath(), "history file; empty keeps history in memory only")
+	if err := e.parse(fs, args); err != nil {
+		return err
+	}
+	r := &repl{e: e, w: e.stdout, ruby: subtitle.RubyBrackets}
+	if err := r.setView(*view); err != nil {
+		return err
+	}
+	if err := r.rebuild(); err != nil {
+		return err
+	}
+	defer func() { r.run.stop() }()
+	if *histPath != "" {
+		r.history = readHistory(*histPath)
+		if f, err := os.OpenFile(*histPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600); err == nil {
+			defer f.Close()
+			r.histLog = f
+		} else {
+			fmt.Fprintln(e.stderr, "warning: history not saved:", err)
+		}
+	}
+
+	fmt.Fprintln(r.w, "japaneseparse interactive mode; :help for commands")
+	sc := bufio.NewScanner(e.stdin)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for {
+		fmt.Fprint(r.w, "> ")
+		if !sc.Scan() {
+			fmt.Fprintln(r.w)
+			return sc.Err()
+		}
+		if err := e.ctx.Err(); err != nil {
+			return nil
+		}
+		quit, err := r.handle(strings.TrimSpace(sc.Text()))
+		if err != nil {
+			fmt.Fprintln(r.w, "error:", err)
+		}
+		if quit {
+			return nil
+		}
+	}
+}
+
+func defaultHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".japaneseparse_history")
+}
+
+func readHistory(path string) []string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var lines []string
+	for _, l := range strings.Split(string(b), "\n") {
+		if l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// rebuild recreates the pipeline after a setting that shapes results changed.
+func (r *repl) rebuild() error {
+	opts, err := r.e.cfg.pipelineOptions()
+	if err != nil {
+		return err
+	}
+	if r.run != nil {
+		r.run.stop()
+	}
+	p := pipeline.New(1, pipeline.DefaultStages(opts)...)
+	p.Start(r.e.ctx)
+	r.run = consume(r.e.ctx, p)
+	return nil
+}
+
+func (r *repl) setView(name string) error {
+	for _, v := range replViews {
+		if v == name {
+			r.view = name
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown view %q (want %s)", name, strings.Join(replViews, ", "))
+}
+
+// handle runs one input line and reports whether the session should end.
+func (r *repl) handle(line string) (bool, error) {
+	if line == "" {
+		return false, nil
+	}
+	// history recall replaces the line before it is recorded
+	if strings.HasPrefix(line, "!") {
+		recalled, err := r.recall(line)
+		if err != nil {
+			return false, err
+		}
+		fmt.Fprintln(r.w, recalled)
+		line = recalled
+	}
+	if line != ":history" {
+		r.history = append(r.history, line)
+		if r.histLog != nil {
+			fmt.Fprintln(r.histLog, line)
+		}
+	}
+	if !strings.HasPrefix(line, ":") {
+		return false, r.analyze(line)
+	}
+
+	fields := strings.Fields(line[1:])
+	if len(fields) == 0 {
+		return false, nil
+	}
+	arg := strings.TrimSpace(strings.TrimPrefix(line[1:], fields[0]))
+	switch cmd := fields[0]; cmd {
+	case "q", "quit", "exit":
+		return true, nil
+	case "help", "h":
+		fmt.Fprint(r.w, replHelp)
+	case "tokens", "merged", "furigana", "glosses", "clauses", "analysis":
+		r.view = cmd
+		r.show()
+	case "view":
+		if err := r.setView(arg); err != nil {
+			return false, err
+		}
+		r.show()
+	case "lookup", "l":
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return false, fmt.Errorf("usage: :lookup N")
+		}
+		return false, r.lookup(n)
+	case "kanji", "k":
+		if arg == "" && r.last != nil {
+			arg = r.last.Sentence.Text
+		}
+		r.kanji(arg)
+	case "mode":
+		if arg != "" {
+			if err := tokenize.SetMode(arg); err != nil {
+				return false, err
+			}
+			r.e.cfg.mode = arg
+			if err := r.rebuild(); err != nil {
+				return false, err
+			}
+		}
+		fmt.Fprintln(r.w, "mode:", tokenize.Mode())
+	case "ruby":
+		if arg != "" {
+			style, err := subtitle.ParseRubyStyle(arg)
+			if err != nil {
+				return false, err
+			}
+			r.ruby = style
+		}
+		fmt.Fprintln(r.w, "ruby:", r.ruby)
+	case "history":
+		for i, h := range r.history {
+			fmt.Fprintf(r.w, "%4d  %s\n", i+1, h)
+		}
+	default:
+		return false, fmt.Errorf("unknown command :%s (:help lists commands)", cmd)
+	}
+	return false, nil
+}
+
+// recall resolves !! and !N against the history.
+func (r *repl) recall(line string) (string, error) {
+	if len(r.history) == 0 {
+		return "", errors.New("history is empty")
+	}
+	if line == "!!" {
+		return r.history[len(r.history)-1], nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 1 || n > len(r.history) {
+		return "", fmt.Errorf("no history entry %s", line[1:])
+	}
+	return r.history[n-1], nil
+}
+
+func (r *repl) analyze(text string) error {
+	// one line at a time: the queue is empty again before the next one
+	if _, err := ingest.IngestSentence(text); err != nil {
+		return err
+	}
+	res, err := r.run.next(r.e.ctx)
+	if err != nil {
+		return err
+	}
+	r.last = res
+	r.show()
+	return nil
+}
+
+// show prints the last sentence in the current view.
+func (r *repl) show() {
+	res := r.last
+	if res == nil {
+		return
+	}
+	switch r.view {
+	case "tokens":
+		fmt.Fprint(r.w, tokenTable(res.Tokens))
+	case "merged":
+		fmt.Fprint(r.w, tokenTable(res.Merged))
+	case "furigana":
+		fmt.Fprintln(r.w, subtitle.Ruby(res.Merged, r.ruby, r.e.cfg.profile()))
+	case "glosses":
+		rows := make([][]string, len(res.Merged))
+		for i, t := range res.Merged {
+			rows[i] = []string{strconv.Itoa(i), t.Text, t.Reading, strings.Join(t.DictionaryEntry.Glosses, "; ")}
+		}
+		fmt.Fprint(r.w, table(rows))
+	case "clauses":
+		if res.Analysis == nil {
+			return
+		}
+		for i, c := range res.Analysis.Clauses() {
+			line := fmt.Sprintf("%d  [%d-%d]  %s", i, c.Start, c.End, joinTokens(res.Merged, c.Start, c.End))
+			if c.Type != "" {
+				line += "  type=" + string(c.Type)
+			}
+			if c.Connective != "" {
+				line += "  connective=" + c.Connective
+			}
+			fmt.Fprintln(r.w, line)
+		}
+	case "analysis":
+		b, _ := json.MarshalIndent(res.Analysis, "", "  ")
+		fmt.Fprintln(r.w, string(b))
+	}
+}
+
+func joinTokens(toks []model.Token, start, end int) string {
+	var sb strings.Builder
+	for i := start; i < end && i < len(toks); i++ {
+		sb.WriteString(toks[i].Text)
+	}
+	return sb.String()
+}
+
+func (r *repl) lookup(n int) error {
+	if r.last == nil {
+		return errors.New("no sentence yet")
+	}
+	if n < 0 || n >= len(r.last.Merged) {
+		return fmt.Errorf("token %d out of range 0-%d", n, len(r.last.Merged)-1)
+	}
+	t := r.last.Merged[n]
+	d := t.DictionaryEntry
+	rows := [][]string{
+		{"surface", t.Text},
+		{"lemma", t.Lemma},
+		{"reading", t.Reading},
+		{"pos", t.POS},
+		{"furigana", subtitle.Ruby([]model.Token{t}, r.ruby, nil)},
+	}
+	if t.InflectionType != "" && t.InflectionType != "*" {
+		rows = append(rows, []string{"inflection", t.InflectionType + " " + t.InflectionForm})
+	}
+	rows = append(rows,
+		[]string{"source", d.Source},
+		[]string{"readings", strings.Join(d.Readings, ", ")},
+		[]string{"glosses", strings.Join(d.Glosses, "; ")},
+	)
+	fmt.Fprint(r.w, table(rows))
+	r.kanji(t.Text)
+	return nil
+}
+
+func (r *repl) kanji(text string) {
+	var rows [][]string
+	seen := make(map[rune]bool)
+	for _, c := range text {
+		if kanji.IsKanji(c) && !seen[c] {
+			seen[c] = true
+			info := kanji.GetKanjiInfo(c)
+			rows = append(rows, []string{info.Kanji, fmt.Sprintf("grade %d", info.Grade), fmt.Sprintf("JLPT %d", info.JLPT), strings.Join(info.Readings, " ")})
+		}
+	}
+	fmt.Fprint(r.w, table(rows))
+}
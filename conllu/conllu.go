@@ -0,0 +1,243 @@
+// Package conllu writes tokens in the CoNLL-U format of Universal Dependencies: one
+// line per word with ID, FORM, LEMMA, UPOS, XPOS, FEATS, HEAD, DEPREL, DEPS and MISC,
+// and sentences separated by blank lines with sent_id and text comments.
+package conllu
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"japaneseparse/analyze"
+	"japaneseparse/model"
+	"japaneseparse/ud"
+)
+
+// Sentence is what the writer needs to know about one sentence.
+type Sentence struct {
+	ID   string
+	Text string // the normalized sentence, written as the text comment
+	// Raw is the sentence as it appeared in the input. Token offsets index it, so it
+	// decides SpaceAfter; empty means Text.
+	Raw        string
+	DocumentID string
+	Paragraph  int
+	Tokens     []model.Token
+	// Analysis supplies HEAD and DEPREL when its clauses record verbs and their
+	// arguments. Its indexes must refer to Tokens (the merged tokens of a pipeline
+	// result); otherwise it is ignored.
+	Analysis *analyze.Analysis
+}
+
+// Writer writes sentences, starting a "# newdoc" or "# newpar" comment whenever the
+// document or paragraph changes.
+type Writer struct {
+	w         io.Writer
+	started   bool
+	document  string
+	paragraph int
+}
+
+// NewWriter returns a writer to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes one sentence followed by a blank line.
+func (cw *Writer) Write(s Sentence) error {
+	var sb strings.Builder
+	if s.DocumentID != "" && (!cw.started || s.DocumentID != cw.document) {
+		fmt.Fprintf(&sb, "# newdoc id = %s\n", s.DocumentID)
+		sb.WriteString("# newpar\n")
+	} else if cw.started && s.Paragraph != cw.paragraph {
+		sb.WriteString("# newpar\n")
+	}
+	cw.started, cw.document, cw.paragraph = true, s.DocumentID, s.Paragraph
+
+	if s.ID != "" {
+		fmt.Fprintf(&sb, "# sent_id = %s\n", s.ID)
+	}
+	fmt.Fprintf(&sb, "# text = %s\n", oneLine(s.Text))
+
+	// whitespace tokens are not words; they show up as the absence of SpaceAfter=No
+	ids := make([]int, len(s.Tokens))
+	lastWord, n := -1, 0
+	for i, t := range s.Tokens {
+		if strings.TrimSpace(t.Text) != "" {
+			n++
+			ids[i], lastWord = n, i
+		}
+	}
+	heads := dependencies(s)
+	raw := []rune(s.Raw)
+	if s.Raw == "" {
+		raw = []rune(s.Text)
+	}
+	for i, t := range s.Tokens {
+		if ids[i] == 0 {
+			continue
+		}
+		head, deprel := "_", "_"
+		if heads != nil {
+			head, deprel = "0", heads[i].deprel
+			if h := heads[i].head; h >= 0 {
+				head = fmt.Sprint(ids[h])
+			}
+		}
+		cols := []string{
+			fmt.Sprint(ids[i]),
+			field(t.Text),
+			field(lemma(t)),
+			ud.UPOS(s.Tokens, i),
+			field(t.POS),
+			feats(s.Tokens, i),
+			head,
+			deprel,
+			"_",
+			misc(t, raw, i == lastWord),
+		}
+		sb.WriteString(strings.Join(cols, "\t"))
+		sb.WriteByte('\n')
+	}
+	sb.WriteByte('\n')
+	_, err := io.WriteString(cw.w, sb.String())
+	return err
+}
+
+// field makes a value safe for a column: no tabs or line breaks, "_" when empty.
+func field(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' {
+			return ' '
+		}
+		return r
+	}, s)
+	if s == "" || s == "*" {
+		return "_"
+	}
+	return s
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(s, "　", " ")), " ")
+}
+
+func lemma(t model.Token) string {
+	if t.Lemma == "" || t.Lemma == "*" {
+		return t.Text
+	}
+	return t.Lemma
+}
+
+// feats returns the UD features of token i.
+func feats(toks []model.Token, i int) string {
+	if f := ud.Feats(toks, i).String(); f != "" {
+		return f
+	}
+	return "_"
+}
+
+// misc records the IPA inflection form and type, the reading, and SpaceAfter=No for
+// words not followed by whitespace in the raw sentence. The inflection values are
+// Japanese, which FEATS does not allow but MISC does.
+func misc(t model.Token, raw []rune, last bool) string {
+	var ms []string
+	for _, kv := range [][2]string{{"InflForm", t.InflectionForm}, {"InflType", t.InflectionType}, {"Reading", t.Reading}} {
+		if v := field(kv[1]); v != "_" {
+			ms = append(ms, kv[0]+"="+strings.ReplaceAll(v, "|", "/"))
+		}
+	}
+	if !last && (t.End < 0 || t.End >= len(raw) || !unicode.IsSpace(raw[t.End])) {
+		ms = append(ms, "SpaceAfter=No")
+	}
+	if len(ms) == 0 {
+		return "_"
+	}
+	return strings.Join(ms, "|")
+}
+
+type dependency struct {
+	head   int // token index, -1 for the root
+	deprel string
+}
+
+// dependencies derives one head per token from the clause roles of the analysis: the
+// verb of the last clause is the root, verbs of earlier clauses attach to the verb of
+// the next clause, and arguments and auxiliaries attach to their clause's verb. Other
+// words attach to their clause's verb (or the root) as dep, or punct for punctuation. It returns nil if the
+// analysis records no verbs.
+func dependencies(s Sentence) []dependency {
+	if s.Analysis == nil || s.Analysis.TokenCount != len(s.Tokens) {
+		return nil
+	}
+	clauses := s.Analysis.Clauses()
+	var verbs []int // verb index per clause, -1 if none
+	root := -1
+	for _, c := range clauses {
+		v := -1
+		if c.Roles.Verb != nil && *c.Roles.Verb >= 0 && *c.Roles.Verb < len(s.Tokens) {
+			v = *c.Roles.Verb
+			root = v
+		}
+		verbs = append(verbs, v)
+	}
+	if root < 0 {
+		return nil
+	}
+
+	deps := make([]dependency, len(s.Tokens))
+	for i := range s.Tokens {
+		deps[i] = dependency{head: root, deprel: "dep"}
+		if ud.UPOS(s.Tokens, i) == "PUNCT" {
+			deps[i].deprel = "punct"
+		}
+	}
+	deps[root] = dependency{head: -1, deprel: "root"}
+	for ci, c := range clauses {
+		v := verbs[ci]
+		if v < 0 {
+			continue
+		}
+		for i := c.Start; i < c.End && i < len(deps); i++ {
+			if i != v {
+				deps[i] = dependency{head: v, deprel: "dep"}
+			}
+		}
+		if v != root {
+			// attach to the verb of the next clause that has one
+			head := root
+			for _, nv := range verbs[ci+1:] {
+				if nv >= 0 {
+					head = nv
+					break
+				}
+			}
+			rel := "advcl"
+			if c.Type == analyze.RelativeClause {
+				rel = "acl"
+			}
+			deps[v] = dependency{head: head, deprel: rel}
+		}
+		roles := c.Roles
+		for _, a := range []struct {
+			idx *[]int
+			rel string
+		}{{roles.Subject, "nsubj"}, {roles.Object, "obj"}, {roles.IndirectObj, "iobj"}, {roles.Adverbial, "advmod"}} {
+			if a.idx == nil {
+				continue
+			}
+			for _, i := range *a.idx {
+				if i >= 0 && i < len(deps) && i != v {
+					deps[i] = dependency{head: v, deprel: a.rel}
+				}
+			}
+		}
+		for _, i := range roles.Auxiliaries {
+			if i >= 0 && i < len(deps) && i != v {
+				deps[i] = dependency{head: v, deprel: "aux"}
+			}
+		}
+	}
+	return deps
+}
@@ -0,0 +1,57 @@
+package conllu
+
+import (
+	"strings"
+	"testing"
+
+	"japaneseparse/analyze"
+	"japaneseparse/model"
+)
+
+func tok(text, lemma, reading, pos string, start int) model.Token {
+	return model.Token{Text: text, Lemma: lemma, Reading: reading, POS: pos, InflectionType: "*", InflectionForm: "*", Start: start, End: start + len([]rune(text))}
+}
+
+func TestWrite(t *testing.T) {
+	verb := tok("降った", "降る", "フッタ", "動詞,自立,*,*", 3)
+	verb.InflectionType, verb.InflectionForm = "五段・ラ行", "連用タ接続"
+	toks := []model.Token{
+		tok("雨", "雨", "アメ", "名詞,一般,*,*", 0),
+		tok("が", "が", "ガ", "助詞,格助詞,一般,*", 1),
+		tok(" ", " ", "", "記号,空白,*,*", 2),
+		verb,
+		tok("。", "。", "。", "記号,句点,*,*", 6),
+	}
+	v := 3
+	subj := []int{0}
+	a := &analyze.Analysis{TokenCount: len(toks), Structure: map[string]interface{}{"clauses": []analyze.Clause{
+		{Start: 0, End: 4, Roles: analyze.ClauseRole{Subject: &subj, Verb: &v}},
+	}}}
+
+	var sb strings.Builder
+	w := NewWriter(&sb)
+	if err := w.Write(Sentence{ID: "s1", Text: "雨が降った。", Raw: "雨が 降った。", DocumentID: "d1", Tokens: toks, Analysis: a}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(Sentence{ID: "s2", Text: "雨", DocumentID: "d1", Paragraph: 1, Tokens: toks[:1]}); err != nil {
+		t.Fatal(err)
+	}
+	want := `# newdoc id = d1
+# newpar
+# sent_id = s1
+# text = 雨が降った。
+1	雨	雨	NOUN	名詞,一般,*,*	_	3	nsubj	_	Reading=アメ|SpaceAfter=No
+2	が	が	ADP	助詞,格助詞,一般,*	_	3	dep	_	Reading=ガ
+3	降った	降る	VERB	動詞,自立,*,*	_	0	root	_	InflForm=連用タ接続|InflType=五段・ラ行|Reading=フッタ|SpaceAfter=No
+4	。	。	PUNCT	記号,句点,*,*	_	3	punct	_	Reading=。
+
+# newpar
+# sent_id = s2
+# text = 雨
+1	雨	雨	NOUN	名詞,一般,*,*	_	_	_	_	Reading=アメ
+
+`
+	if got := sb.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
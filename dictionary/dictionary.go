@@ -2,22 +2,197 @@ package dictionary
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"japaneseparse/furigana"
 	"japaneseparse/model"
 	"japaneseparse/tokenize"
+
+	jmdict "github.com/yomidevs/jmdict-go"
 )
 
+// version identifies the dictionary files passed to InitDictionaries.
+var version = "none"
+
+// index maps kanji forms and readings to the entries that list them.
+type index map[string][]model.DictionaryEntry
+
+var (
+	mu        sync.RWMutex
+	jmIndex   index
+	enamIndex index
+	// loaded is set once InitDictionaries has parsed every dictionary file.
+	loaded bool
+)
+
+// InitDictionaries parses the JMdict and JMnedict (ENAMDICT) XML files and indexes
+// their entries by kanji form and reading. A dictionary that loads is used even if the
+// other does not; Loaded stays false, and an error names the files, unless both load.
 func InitDictionaries(jmdictPath, enamdictPath string) error {
-	// If LoadJMdict is not exported, inline its logic or export it
-	return nil // TODO: Replace with actual loading logic
+	jm, jmErr := loadFile(jmdictPath, loadJMdict)
+	enam, enamErr := loadFile(enamdictPath, loadJMnedict)
+
+	mu.Lock()
+	defer mu.Unlock()
+	version = filesVersion(jmdictPath, enamdictPath)
+	jmIndex, enamIndex = jm, enam
+	loaded = jmErr == nil && enamErr == nil
+	var failed []string
+	for _, err := range []error{jmErr, enamErr} {
+		if err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("dictionaries unavailable: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// loadFile opens path and indexes it with load. A file without entries is an error:
+// the XML decoder stops silently at the first malformed token.
+func loadFile(path string, load func(io.Reader) (index, error)) (index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	idx, err := load(f)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if len(idx) == 0 {
+		return nil, fmt.Errorf("parse %s: no entries", path)
+	}
+	return idx, nil
+}
+
+func loadJMdict(r io.Reader) (index, error) {
+	dict, _, err := jmdict.LoadJmdict(r)
+	if err != nil {
+		return nil, err
+	}
+	idx := make(index)
+	for _, e := range dict.Entries {
+		entry := model.DictionaryEntry{Source: "JMdict"}
+		var keys []string
+		for _, k := range e.Kanji {
+			entry.Kanji = append(entry.Kanji, k.Expression)
+			entry.IsCommon = entry.IsCommon || isCommon(k.Priorities)
+			keys = append(keys, k.Expression)
+		}
+		for _, rd := range e.Readings {
+			entry.Readings = append(entry.Readings, rd.Reading)
+			entry.IsCommon = entry.IsCommon || isCommon(rd.Priorities)
+			keys = append(keys, rd.Reading)
+		}
+		for _, sense := range e.Sense {
+			for _, g := range sense.Glossary {
+				entry.Glosses = append(entry.Glosses, g.Content)
+			}
+			entry.POS = append(entry.POS, sense.PartsOfSpeech...)
+		}
+		idx.add(keys, entry)
+	}
+	return idx, nil
+}
+
+func loadJMnedict(r io.Reader) (index, error) {
+	dict, _, err := jmdict.LoadJmnedict(r)
+	if err != nil {
+		return nil, err
+	}
+	idx := make(index)
+	for _, e := range dict.Entries {
+		entry := model.DictionaryEntry{Source: "ENAMDICT", IsName: true}
+		var keys []string
+		for _, k := range e.Kanji {
+			entry.Kanji = append(entry.Kanji, k.Expression)
+			keys = append(keys, k.Expression)
+		}
+		for _, rd := range e.Readings {
+			entry.Readings = append(entry.Readings, rd.Reading)
+			keys = append(keys, rd.Reading)
+		}
+		for _, t := range e.Translations {
+			entry.Glosses = append(entry.Glosses, t.Translations...)
+			entry.POS = append(entry.POS, t.NameTypes...)
+		}
+		idx.add(keys, entry)
+	}
+	return idx, nil
+}
+
+func (idx index) add(keys []string, entry model.DictionaryEntry) {
+	for _, k := range keys {
+		idx[k] = append(idx[k], entry)
+	}
+}
+
+// isCommon reports whether the priority codes mark a word as common, as JMdict's (P).
+func isCommon(priorities []string) bool {
+	for _, p := range priorities {
+		switch p {
+		case "news1", "ichi1", "spec1", "spec2", "gai1":
+			return true
+		}
+	}
+	return false
+}
+
+// Loaded reports whether InitDictionaries has loaded both dictionaries.
+func Loaded() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return loaded
+}
+
+// Version identifies the loaded dictionaries by file name, size and modification time,
+// so caches can tell when the dictionaries change. It is "none" before
+// InitDictionaries.
+func Version() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return version
+}
+
+func filesVersion(paths ...string) string {
+	parts := make([]string, len(paths))
+	for i, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			parts[i] = filepath.Base(p) + ":missing"
+			continue
+		}
+		parts[i] = fmt.Sprintf("%s:%d:%d", filepath.Base(p), fi.Size(), fi.ModTime().Unix())
+	}
+	return strings.Join(parts, ";")
 }
 
 func DebugGlossaryFields() {
 	// No-op or add debug logic if needed
 }
 
+// LookupDictionary returns an entry for each token: the JMdict entry for its surface or
+// lemma, else the ENAMDICT one, preferring entries that list the token's reading. Tokens
+// without an entry get a placeholder with Source "none".
 func LookupDictionary(ctx context.Context, tokens []tokenize.Token) ([]model.DictionaryEntry, error) {
+	mu.RLock()
+	defer mu.RUnlock()
 	entries := make([]model.DictionaryEntry, len(tokens))
 	for i, t := range tokens {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if e, ok := lookup(t); ok {
+			entries[i] = e
+			continue
+		}
 		entries[i] = model.DictionaryEntry{
 			Kanji:    []string{t.Text},
 			Readings: []string{t.Reading},
@@ -27,3 +202,30 @@ func LookupDictionary(ctx context.Context, tokens []tokenize.Token) ([]model.Dic
 	}
 	return entries, nil
 }
+
+func lookup(t tokenize.Token) (model.DictionaryEntry, bool) {
+	reading := furigana.KatakanaToHiragana(t.Reading)
+	for _, idx := range []index{jmIndex, enamIndex} {
+		for _, key := range []string{t.Text, t.Lemma} {
+			if key == "" {
+				continue
+			}
+			if cands := idx[key]; len(cands) > 0 {
+				return pick(cands, reading), true
+			}
+		}
+	}
+	return model.DictionaryEntry{}, false
+}
+
+// pick returns the first candidate listing reading (hiragana), else the first one.
+func pick(cands []model.DictionaryEntry, reading string) model.DictionaryEntry {
+	for _, c := range cands {
+		for _, r := range c.Readings {
+			if furigana.KatakanaToHiragana(r) == reading {
+				return c
+			}
+		}
+	}
+	return cands[0]
+}
@@ -0,0 +1,76 @@
+package dictionary
+
+import (
+	"context"
+	"testing"
+
+	"japaneseparse/tokenize"
+)
+
+func TestLookupDictionary(t *testing.T) {
+	if err := InitDictionaries("testdata/missing_jmdict.xml", "testdata/enamdict_sample.xml"); err == nil || Loaded() {
+		t.Fatalf("InitDictionaries without JMdict: err %v, loaded %v", err, Loaded())
+	}
+	if err := InitDictionaries("testdata/jmdict_sample.xml", "testdata/enamdict_sample.xml"); err != nil || !Loaded() {
+		t.Fatalf("InitDictionaries: err %v, loaded %v", err, Loaded())
+	}
+
+	toks := []tokenize.Token{
+		{Text: "雨", Lemma: "雨", Reading: "アメ"},
+		{Text: "降っ", Lemma: "降る", Reading: "フッ"}, // found by lemma
+		{Text: "心", Lemma: "心", Reading: "シン"},   // the entry listing the token's reading wins
+		{Text: "京都", Lemma: "京都", Reading: "キョウト"},
+		{Text: "猫", Lemma: "猫", Reading: "ネコ"},
+	}
+	entries, err := LookupDictionary(context.Background(), toks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ source, gloss string }{
+		{"JMdict", "rain"},
+		{"JMdict", "to fall"},
+		{"JMdict", "core"},
+		{"ENAMDICT", "Kyoto"},
+		{"none", "<no definition found>"},
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.Source != w.source || len(e.Glosses) == 0 || e.Glosses[0] != w.gloss {
+			t.Errorf("%s: got %+v, want %s %q", toks[i].Text, e, w.source, w.gloss)
+		}
+	}
+	if !entries[0].IsCommon || entries[2].IsCommon || !entries[3].IsName {
+		t.Errorf("flags: 雨 common %v, 心(しん) common %v, 京都 name %v", entries[0].IsCommon, entries[2].IsCommon, entries[3].IsName)
+	}
+}
+
+// 心太 (ところてん) is not on the curated jukujikun list and cannot be split per kanji, so
+// only its JMdict entry lets the dictionary aligner give it one word-level ruby.
+func TestJMdictConfirmsWordLevelRuby(t *testing.T) {
+	if err := InitDictionaries("testdata/jmdict_sample.xml", "testdata/enamdict_sample.xml"); err != nil {
+		t.Fatal(err)
+	}
+	toks, err := tokenize.Tokenize(context.Background(), "心太を食べた")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if toks[0].Text != "心太" {
+		t.Fatalf("tokens = %+v", toks)
+	}
+	without := tokenize.UpdateFuriganaFromDictionary(append([]tokenize.Token(nil), toks...))
+	if without[0].Jukujikun {
+		t.Errorf("心太 flagged as jukujikun without a dictionary entry")
+	}
+
+	entries, err := LookupDictionary(context.Background(), toks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range toks {
+		toks[i].DictionaryEntry = entries[i]
+	}
+	toks = tokenize.UpdateFuriganaFromDictionary(toks)
+	if !toks[0].Jukujikun || toks[0].FuriganaText != "[ところてん]" {
+		t.Errorf("with JMdict: furigana %q, jukujikun %v; want one ruby over 心太", toks[0].FuriganaText, toks[0].Jukujikun)
+	}
+}
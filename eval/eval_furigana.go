@@ -0,0 +1,518 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"japaneseparse/furigana"
+	"japaneseparse/kanji"
+)
+
+// Evaluates the default furigana aligner against a gold corpus and optionally
+// compares the result with a saved baseline.
+//
+//	go run ./eval -gold eval/furigana_gold.tsv -baseline eval/baseline.json
+//	go run ./eval -save-baseline eval/baseline.json
+
+// segment is one ruby group (kanji surface + reading) or a run of plain kana.
+type segment struct {
+	Surface string
+	Reading string
+}
+
+type goldEntry struct {
+	Row        int // line in the gold file
+	Surface    string
+	Reading    string
+	Expected   []segment
+	Categories []string
+}
+
+// Result summarises one evaluation run. It is also the on-disk baseline format.
+type Result struct {
+	Words           int            `json:"words"`
+	WordsCorrect    int            `json:"words_correct"`
+	Kanji           int            `json:"kanji"`
+	KanjiCorrect    int            `json:"kanji_correct"`
+	WordAccuracy    float64        `json:"word_accuracy"`
+	KanjiAccuracy   float64        `json:"kanji_accuracy"`
+	CategoryTotal   map[string]int `json:"category_total"`   // gold words per phenomenon
+	CategoryCorrect map[string]int `json:"category_correct"` // correctly aligned words per phenomenon
+	MismatchCount   map[string]int `json:"mismatch_count"`   // failed words per kind of mismatch
+	Failures        []Failure      `json:"failures"`
+}
+
+// Failure is a gold word the aligner got wrong. Row identifies it, since a surface may
+// appear in the gold file more than once.
+type Failure struct {
+	Row     int    `json:"row"`
+	Surface string `json:"surface"`
+	Kind    string `json:"kind"`
+}
+
+// Kinds of mismatch, in the order they are reported. classify picks the first that
+// applies.
+var mismatchKinds = []string{"unaligned", "jukujikun", "grouping", "okurigana", "rendaku", "gemination", "reading"}
+
+// categoryOrder lists the gold categories in the order they are reported; others
+// follow alphabetically.
+var categoryOrder = []string{"rendaku", "gemination", "okurigana", "jukujikun"}
+
+func main() {
+	goldPath := flag.String("gold", "eval/furigana_gold.tsv", "gold corpus (TSV)")
+	kanjidicPath := flag.String("kanjidic", "dict/kanjidic2.xml", "path to kanjidic2.xml")
+	baselinePath := flag.String("baseline", "", "compare against this baseline JSON")
+	savePath := flag.String("save-baseline", "", "write this run's result as a baseline JSON")
+	verbose := flag.Bool("v", false, "print every failing word and aligner logs")
+	flag.Parse()
+
+	if !*verbose {
+		// kanji lookups log every reading; keep the report readable
+		log.SetOutput(io.Discard)
+	}
+	if err := kanji.InitKanjidic2(*kanjidicPath); err != nil {
+		fmt.Printf("Warning: failed to init kanjidic2: %v\n", err)
+	}
+
+	gold, err := readGold(*goldPath)
+	if err != nil {
+		fmt.Println("failed to read gold corpus:", err)
+		os.Exit(1)
+	}
+
+	res := evaluate(gold, furigana.Align, *verbose)
+	printResult(os.Stdout, res)
+
+	if *savePath != "" {
+		data, _ := json.MarshalIndent(res, "", "  ")
+		if err := os.WriteFile(*savePath, data, 0644); err != nil {
+			fmt.Println("failed to write baseline:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\nBaseline written to %s\n", *savePath)
+	}
+
+	if *baselinePath != "" {
+		data, err := os.ReadFile(*baselinePath)
+		if err != nil {
+			fmt.Println("failed to read baseline:", err)
+			os.Exit(1)
+		}
+		var base Result
+		if err := json.Unmarshal(data, &base); err != nil {
+			fmt.Println("failed to parse baseline:", err)
+			os.Exit(1)
+		}
+		if regressions := compareBaseline(base, res); regressions > 0 {
+			os.Exit(1)
+		}
+	}
+}
+
+// readGold parses the gold TSV, skipping blank lines and # comments.
+func readGold(path string) ([]goldEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []goldEntry
+	sc := bufio.NewScanner(f)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		cols := strings.Split(text, "\t")
+		if len(cols) < 3 {
+			return nil, fmt.Errorf("%s:%d: expected at least 3 tab-separated columns", path, line)
+		}
+		e := goldEntry{
+			Row:      line,
+			Surface:  cols[0],
+			Reading:  cols[1],
+			Expected: parseSegmentation(cols[2]),
+		}
+		if joined := joinSurface(e.Expected); joined != e.Surface {
+			return nil, fmt.Errorf("%s:%d: segmentation %q does not spell %q", path, line, cols[2], e.Surface)
+		}
+		if len(cols) > 3 && strings.TrimSpace(cols[3]) != "" {
+			e.Categories = strings.Split(strings.TrimSpace(cols[3]), ",")
+		} else {
+			e.Categories = inferCategories(e.Expected)
+		}
+		out = append(out, e)
+	}
+	return out, sc.Err()
+}
+
+// parseSegmentation turns "見=み る" into segments, merging adjacent plain kana.
+func parseSegmentation(s string) []segment {
+	var out []segment
+	for _, chunk := range strings.Fields(s) {
+		if idx := strings.Index(chunk, "="); idx >= 0 {
+			out = append(out, segment{Surface: chunk[:idx], Reading: chunk[idx+1:]})
+			continue
+		}
+		out = appendPlain(out, chunk)
+	}
+	return out
+}
+
+// pairsToSegments converts aligner output into segments comparable with the gold data.
+func pairsToSegments(pairs [][2]string) []segment {
+	var out []segment
+	for _, p := range pairs {
+		if p[0] == "" {
+			// leftover reading the aligner could not place
+			out = append(out, segment{Reading: p[1]})
+			continue
+		}
+		if kanji.IsKanji([]rune(p[0])[0]) {
+			out = append(out, segment{Surface: p[0], Reading: p[1]})
+			continue
+		}
+		out = appendPlain(out, p[0])
+	}
+	return out
+}
+
+func appendPlain(segs []segment, s string) []segment {
+	if n := len(segs); n > 0 && segs[n-1].Reading == "" && segs[n-1].Surface != "" && !kanji.IsKanji([]rune(segs[n-1].Surface)[0]) {
+		segs[n-1].Surface += s
+		return segs
+	}
+	return append(segs, segment{Surface: s})
+}
+
+func joinSurface(segs []segment) string {
+	var sb strings.Builder
+	for _, s := range segs {
+		sb.WriteString(s.Surface)
+	}
+	return sb.String()
+}
+
+// kanjiSpans maps each kanji rune offset in the surface to the ruby group covering it.
+func kanjiSpans(segs []segment) map[int]segment {
+	out := make(map[int]segment)
+	offset := 0
+	for _, s := range segs {
+		runes := []rune(s.Surface)
+		for i, r := range runes {
+			if kanji.IsKanji(r) {
+				out[offset+i] = s
+			}
+		}
+		offset += len(runes)
+	}
+	return out
+}
+
+// inferCategories derives the alignment phenomena present in a gold segmentation.
+func inferCategories(segs []segment) []string {
+	var cats []string
+	has := map[string]bool{}
+	add := func(c string) {
+		if !has[c] {
+			has[c] = true
+			cats = append(cats, c)
+		}
+	}
+	sawKanji := false
+	for _, s := range segs {
+		runes := []rune(s.Surface)
+		if len(runes) == 0 {
+			continue
+		}
+		if !kanji.IsKanji(runes[0]) {
+			if sawKanji {
+				add("okurigana")
+			}
+			continue
+		}
+		if len(runes) > 1 {
+			add("jukujikun")
+		}
+		reading := []rune(s.Reading)
+		if len(reading) > 0 {
+			if sawKanji && strings.ContainsRune("がぎぐげござじずぜぞだぢづでどばびぶべぼ", reading[0]) {
+				add("rendaku")
+			}
+			if reading[len(reading)-1] == 'っ' {
+				add("gemination")
+			}
+		}
+		sawKanji = true
+	}
+	if len(cats) == 0 {
+		cats = append(cats, "other")
+	}
+	return cats
+}
+
+// evaluate aligns every gold word with align and scores the result.
+func evaluate(gold []goldEntry, align func(surface, reading string) [][2]string, verbose bool) Result {
+	res := Result{
+		CategoryTotal:   make(map[string]int),
+		CategoryCorrect: make(map[string]int),
+		MismatchCount:   make(map[string]int),
+	}
+	for _, g := range gold {
+		actual := pairsToSegments(align(g.Surface, g.Reading))
+		want := kanjiSpans(g.Expected)
+		got := kanjiSpans(actual)
+
+		for idx, w := range want {
+			res.Kanji++
+			if got[idx] == w {
+				res.KanjiCorrect++
+			}
+		}
+
+		res.Words++
+		for _, c := range g.Categories {
+			res.CategoryTotal[c]++
+		}
+		if segmentsEqual(g.Expected, actual) {
+			res.WordsCorrect++
+			for _, c := range g.Categories {
+				res.CategoryCorrect[c]++
+			}
+			continue
+		}
+		kind := classify(g.Expected, actual)
+		res.MismatchCount[kind]++
+		res.Failures = append(res.Failures, Failure{Row: g.Row, Surface: g.Surface, Kind: kind})
+		if verbose {
+			fmt.Printf("FAIL %d: %s (%s) [%s]\n  want: %s\n  got:  %s\n",
+				g.Row, g.Surface, g.Reading, kind, formatSegments(g.Expected), formatSegments(actual))
+		}
+	}
+	if res.Words > 0 {
+		res.WordAccuracy = float64(res.WordsCorrect) / float64(res.Words)
+	}
+	if res.Kanji > 0 {
+		res.KanjiAccuracy = float64(res.KanjiCorrect) / float64(res.Kanji)
+	}
+	sort.Slice(res.Failures, func(i, j int) bool { return res.Failures[i].Row < res.Failures[j].Row })
+	return res
+}
+
+// classify names what went wrong in a failed alignment:
+//
+//	unaligned   reading left over, or a kanji without a reading
+//	jukujikun   a word-level ruby over several kanji was split or regrouped
+//	grouping    kanji grouped differently otherwise
+//	okurigana   the kana between or after the kanji differ
+//	rendaku     a kanji reading differs only in the voicing of its first kana
+//	gemination  a kanji reading differs where one of them ends in っ
+//	reading     any other wrong kanji reading
+func classify(want, got []segment) string {
+	for _, s := range got {
+		if s.Surface == "" || isKanjiSegment(s) && s.Reading == "" {
+			return "unaligned"
+		}
+	}
+	if !sameShape(want, got, isKanjiSegment) {
+		gotSurfaces := make(map[string]bool)
+		for _, s := range got {
+			gotSurfaces[s.Surface] = true
+		}
+		for _, s := range want {
+			if isKanjiSegment(s) && len([]rune(s.Surface)) > 1 && !gotSurfaces[s.Surface] {
+				return "jukujikun"
+			}
+		}
+		return "grouping"
+	}
+	if !sameShape(want, got, func(segment) bool { return true }) {
+		return "okurigana"
+	}
+	kind := "reading"
+	for i := range want {
+		w, g := want[i], got[i]
+		if w == g {
+			continue
+		}
+		if !isKanjiSegment(w) {
+			return "okurigana"
+		}
+		switch {
+		case unvoice(w.Reading) == unvoice(g.Reading):
+			kind = "rendaku"
+		case strings.HasSuffix(w.Reading, "っ") || strings.HasSuffix(g.Reading, "っ"):
+			kind = "gemination"
+		default:
+			return "reading"
+		}
+	}
+	return kind
+}
+
+func isKanjiSegment(s segment) bool {
+	return s.Surface != "" && kanji.IsKanji([]rune(s.Surface)[0])
+}
+
+// sameShape reports whether want and got have the same surfaces for the segments keep
+// selects.
+func sameShape(want, got []segment, keep func(segment) bool) bool {
+	var a, b []string
+	for _, s := range want {
+		if keep(s) {
+			a = append(a, s.Surface)
+		}
+	}
+	for _, s := range got {
+		if keep(s) {
+			b = append(b, s.Surface)
+		}
+	}
+	return strings.Join(a, "|") == strings.Join(b, "|")
+}
+
+// unvoice maps a reading starting with a voiced or semi-voiced kana to its plain form,
+// so readings differing only in voicing compare equal.
+func unvoice(s string) string {
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return s
+	}
+	const voiced, plain = "がぎぐげござじずぜぞだぢづでどばびぶべぼぱぴぷぺぽ", "かきくけこさしすせそたちつてとはひふへほはひふへほ"
+	v, p := []rune(voiced), []rune(plain)
+	for i := range v {
+		if runes[0] == v[i] {
+			runes[0] = p[i]
+			break
+		}
+	}
+	return string(runes)
+}
+
+func segmentsEqual(a, b []segment) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func formatSegments(segs []segment) string {
+	parts := make([]string, 0, len(segs))
+	for _, s := range segs {
+		if s.Reading != "" {
+			parts = append(parts, s.Surface+"="+s.Reading)
+		} else {
+			parts = append(parts, s.Surface)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func printResult(w io.Writer, res Result) {
+	fmt.Fprintf(w, "Words: %d/%d correct (%.1f%%)\n", res.WordsCorrect, res.Words, 100*res.WordAccuracy)
+	fmt.Fprintf(w, "Kanji: %d/%d correct (%.1f%%)\n", res.KanjiCorrect, res.Kanji, 100*res.KanjiAccuracy)
+	if len(res.CategoryTotal) > 0 {
+		fmt.Fprintln(w, "Accuracy by category:")
+		for _, c := range categories(res.CategoryTotal) {
+			total, correct := res.CategoryTotal[c], res.CategoryCorrect[c]
+			fmt.Fprintf(w, "  %-10s %d/%d (%.1f%%)\n", c, correct, total, 100*float64(correct)/float64(total))
+		}
+	}
+	if len(res.Failures) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Failures by kind of mismatch:")
+	for _, k := range mismatchKinds {
+		if n := res.MismatchCount[k]; n > 0 {
+			fmt.Fprintf(w, "  %-10s %d\n", k, n)
+		}
+	}
+}
+
+// categories returns the categories of totals in report order.
+func categories(totals map[string]int) []string {
+	var out, rest []string
+	for _, c := range categoryOrder {
+		if totals[c] > 0 {
+			out = append(out, c)
+		}
+	}
+	for c := range totals {
+		if !containsString(categoryOrder, c) {
+			rest = append(rest, c)
+		}
+	}
+	sort.Strings(rest)
+	return append(out, rest...)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// compareBaseline prints accuracy deltas and the gold rows that regressed or were
+// fixed relative to base. It returns the number of regressions.
+func compareBaseline(base, cur Result) int {
+	fmt.Printf("\nCompared to baseline:\n")
+	fmt.Printf("  word accuracy:  %.1f%% -> %.1f%% (%+.1f)\n", 100*base.WordAccuracy, 100*cur.WordAccuracy, 100*(cur.WordAccuracy-base.WordAccuracy))
+	fmt.Printf("  kanji accuracy: %.1f%% -> %.1f%% (%+.1f)\n", 100*base.KanjiAccuracy, 100*cur.KanjiAccuracy, 100*(cur.KanjiAccuracy-base.KanjiAccuracy))
+
+	regressions, fixed := diffFailures(base.Failures, cur.Failures)
+	if len(fixed) > 0 {
+		fmt.Printf("  fixed:       %s\n", formatFailures(fixed))
+	}
+	if len(regressions) > 0 {
+		fmt.Printf("  REGRESSIONS: %s\n", formatFailures(regressions))
+	}
+	return len(regressions)
+}
+
+// diffFailures returns the failures of cur whose rows did not fail in base, and those
+// of base whose rows no longer fail.
+func diffFailures(base, cur []Failure) (regressions, fixed []Failure) {
+	baseRows := make(map[int]bool, len(base))
+	for _, f := range base {
+		baseRows[f.Row] = true
+	}
+	curRows := make(map[int]bool, len(cur))
+	for _, f := range cur {
+		curRows[f.Row] = true
+		if !baseRows[f.Row] {
+			regressions = append(regressions, f)
+		}
+	}
+	for _, f := range base {
+		if !curRows[f.Row] {
+			fixed = append(fixed, f)
+		}
+	}
+	return regressions, fixed
+}
+
+func formatFailures(fs []Failure) string {
+	parts := make([]string, len(fs))
+	for i, f := range fs {
+		parts[i] = fmt.Sprintf("%d:%s", f.Row, f.Surface)
+	}
+	return strings.Join(parts, " ")
+}
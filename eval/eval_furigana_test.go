@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		want, got string
+		kind      string
+	}{
+		{"学=がっ 校=こう", "学=がく 校=こう", "gemination"},
+		{"手=て 紙=がみ", "手=て 紙=かみ", "rendaku"},
+		{"発=はっ 表=ぴょう", "発=はっ 表=ひょう", "rendaku"},
+		{"今日=きょう", "今=きょ 日=う", "jukujikun"},
+		{"学=がっ 校=こう", "学校=がっこう", "grouping"},
+		{"見=み る", "見=みる", "okurigana"},
+		{"入=い り 口=ぐち", "入=いり 口=ぐち", "okurigana"},
+		{"水=すい 位=い", "水 位=すいい", "unaligned"},
+		{"市=し 内=ない", "市=しな 内=い", "reading"},
+	}
+	for _, c := range cases {
+		if got := classify(parseSegmentation(c.want), parseSegmentation(c.got)); got != c.kind {
+			t.Errorf("want %s, got %s: kind %s, want %s", c.want, c.got, got, c.kind)
+		}
+	}
+}
+
+func TestEvaluateKeysFailuresByRow(t *testing.T) {
+	gold := []goldEntry{
+		{Row: 3, Surface: "手紙", Reading: "てがみ", Expected: parseSegmentation("手=て 紙=がみ"), Categories: []string{"rendaku"}},
+		{Row: 4, Surface: "学校", Reading: "がっこう", Expected: parseSegmentation("学=がっ 校=こう"), Categories: []string{"gemination"}},
+		{Row: 7, Surface: "手紙", Reading: "てがみ", Expected: parseSegmentation("手=て 紙=がみ"), Categories: []string{"rendaku"}},
+	}
+	// an aligner that gets 学校 right and never voices
+	align := func(surface, reading string) [][2]string {
+		if surface == "学校" {
+			return [][2]string{{"学", "がっ"}, {"校", "こう"}}
+		}
+		return [][2]string{{"手", "て"}, {"紙", "かみ"}}
+	}
+	res := evaluate(gold, align, false)
+	if res.Words != 3 || res.WordsCorrect != 1 || res.Kanji != 6 || res.KanjiCorrect != 4 {
+		t.Errorf("counts = %+v", res)
+	}
+	if res.MismatchCount["rendaku"] != 2 || len(res.MismatchCount) != 1 || res.CategoryTotal["rendaku"] != 2 {
+		t.Errorf("mismatches = %v, categories = %v", res.MismatchCount, res.CategoryTotal)
+	}
+	if len(res.Failures) != 2 || res.Failures[0].Row != 3 || res.Failures[1].Row != 7 {
+		t.Fatalf("failures = %+v", res.Failures)
+	}
+
+	// the second 手紙 row is fixed and 学校 regresses; the shared surface does not hide either
+	base := res.Failures
+	cur := []Failure{{Row: 3, Surface: "手紙", Kind: "rendaku"}, {Row: 4, Surface: "学校", Kind: "gemination"}}
+	regressions, fixed := diffFailures(base, cur)
+	if len(regressions) != 1 || regressions[0].Row != 4 || len(fixed) != 1 || fixed[0].Row != 7 {
+		t.Errorf("regressions = %+v, fixed = %+v", regressions, fixed)
+	}
+}
+
+func TestPrintResultByCategory(t *testing.T) {
+	gold := []goldEntry{
+		{Row: 1, Surface: "手紙", Reading: "てがみ", Expected: parseSegmentation("手=て 紙=がみ"), Categories: []string{"rendaku"}},
+		{Row: 2, Surface: "今日", Reading: "きょう", Expected: parseSegmentation("今日=きょう"), Categories: []string{"jukujikun"}},
+		{Row: 3, Surface: "大人", Reading: "おとな", Expected: parseSegmentation("大人=おとな"), Categories: []string{"jukujikun"}},
+	}
+	// gets 手紙 and 大人 right and splits 今日
+	align := func(surface, reading string) [][2]string {
+		switch surface {
+		case "手紙":
+			return [][2]string{{"手", "て"}, {"紙", "がみ"}}
+		case "今日":
+			return [][2]string{{"今", "きょ"}, {"日", "う"}}
+		}
+		return [][2]string{{surface, reading}}
+	}
+	var buf strings.Builder
+	printResult(&buf, evaluate(gold, align, false))
+	want := `Words: 2/3 correct (66.7%)
+Kanji: 4/6 correct (66.7%)
+Accuracy by category:
+  rendaku    1/1 (100.0%)
+  jukujikun  1/2 (50.0%)
+Failures by kind of mismatch:
+  jukujikun  1
+`
+	if buf.String() != want {
+		t.Errorf("report:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
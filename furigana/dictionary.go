@@ -0,0 +1,141 @@
+package furigana
+
+import (
+	"japaneseparse/kanji"
+	"japaneseparse/model"
+)
+
+// Dictionary aligns using the word's reading as a whole. Kana in the surface act as
+// anchors that split the reading between kanji runs. A single kanji gets the reading of
+// its run; a run of several kanji is split per kanji with Kanjidic2 when possible and
+// otherwise gets one word-level ruby, provided the curated jukujikun list or the JMdict
+// entry confirms the surface/reading pair. Such words are flagged as jukujikun.
+//
+// When the input has no reading (e.g. unknown words), the first reading of a JMdict
+// entry for the surface is used.
+type Dictionary struct{}
+
+func (Dictionary) String() string { return "dictionary" }
+
+// Align implements Aligner.
+func (Dictionary) Align(in Input) (Result, bool) {
+	reading := KatakanaToHiragana(in.Reading)
+	if reading == "" && in.Entry.Source == "JMdict" && len(in.Entry.Readings) > 0 && containsString(in.Entry.Kanji, in.Surface) {
+		reading = KatakanaToHiragana(in.Entry.Readings[0])
+	}
+	if reading == "" {
+		return Result{}, false
+	}
+
+	runs := splitRuns(in.Surface)
+	runReadings, ok := matchRuns(runs, []rune(reading))
+	if !ok {
+		return Result{}, false
+	}
+	confirmed := IsJMdictPair(in.Surface, reading, in.Entry)
+
+	var res Result
+	for i, run := range runs {
+		if !run.kanji {
+			for _, r := range run.text {
+				res.Pairs = append(res.Pairs, [2]string{string(r), ""})
+			}
+			continue
+		}
+		runReading := runReadings[i]
+		if kanji.IsJukujikun(run.text, runReading) {
+			res.Pairs = append(res.Pairs, [2]string{run.text, runReading})
+			res.Jukujikun = true
+			continue
+		}
+		if len([]rune(run.text)) == 1 {
+			res.Pairs = append(res.Pairs, [2]string{run.text, runReading})
+			continue
+		}
+		if sub, subOK := (Kanjidic{}).Align(Input{Surface: run.text, Reading: runReading}); subOK {
+			res.Pairs = append(res.Pairs, sub.Pairs...)
+			continue
+		}
+		if !confirmed {
+			return Result{}, false
+		}
+		res.Pairs = append(res.Pairs, [2]string{run.text, runReading})
+		res.Jukujikun = true
+	}
+	return res, true
+}
+
+// IsJMdictPair reports whether entry is a JMdict entry listing surface as a kanji form
+// and reading as one of its readings.
+func IsJMdictPair(surface, reading string, entry model.DictionaryEntry) bool {
+	if entry.Source != "JMdict" || !containsString(entry.Kanji, surface) {
+		return false
+	}
+	reading = KatakanaToHiragana(reading)
+	for _, r := range entry.Readings {
+		if KatakanaToHiragana(r) == reading {
+			return true
+		}
+	}
+	return false
+}
+
+// run is a maximal sequence of kanji (including 々) or of other characters.
+type run struct {
+	text  string
+	kanji bool
+}
+
+func splitRuns(surface string) []run {
+	var runs []run
+	for _, r := range surface {
+		isK := IsKanji(r) || r == '々'
+		if n := len(runs); n > 0 && runs[n-1].kanji == isK {
+			runs[n-1].text += string(r)
+			continue
+		}
+		runs = append(runs, run{text: string(r), kanji: isK})
+	}
+	return runs
+}
+
+// matchRuns distributes reading over runs: non-kanji runs must match literally (as
+// hiragana) and each kanji run takes at least one kana. Shorter kanji readings are
+// tried first, backtracking as needed. It returns the reading of each run.
+func matchRuns(runs []run, reading []rune) ([]string, bool) {
+	out := make([]string, len(runs))
+	var match func(i, k int) bool
+	match = func(i, k int) bool {
+		if i == len(runs) {
+			return k == len(reading)
+		}
+		if !runs[i].kanji {
+			lit := []rune(KatakanaToHiragana(runs[i].text))
+			if k+len(lit) > len(reading) || string(reading[k:k+len(lit)]) != string(lit) {
+				return false
+			}
+			out[i] = string(lit)
+			return match(i+1, k+len(lit))
+		}
+		for end := k + 1; end <= len(reading); end++ {
+			out[i] = string(reading[k:end])
+			if match(i+1, end) {
+				return true
+			}
+		}
+		return false
+	}
+	if !match(0, 0) {
+		return nil, false
+	}
+	return out, true
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
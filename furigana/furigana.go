@@ -0,0 +1,181 @@
+// Package furigana aligns readings to the kanji of a word. Every caller that needs
+// furigana (tokenize, the demo, the evaluation command and tests) goes through an
+// Aligner from this package so that all of them behave the same way.
+package furigana
+
+import (
+	"fmt"
+	"strings"
+
+	"japaneseparse/model"
+)
+
+// Input is a word to align: its surface, its reading (katakana or hiragana) and the
+// dictionary entry attached to the token, if any.
+type Input struct {
+	Surface string
+	Reading string
+	Entry   model.DictionaryEntry
+}
+
+// Result is an alignment as [surface, furigana] pairs. Kanji pairs carry the reading of
+// that kanji (or of the whole kanji run for word-level ruby); kana and other characters
+// have an empty reading. A pair with an empty surface holds reading that could not be
+// placed.
+type Result struct {
+	Pairs     [][2]string
+	Jukujikun bool
+}
+
+// Aligner is a furigana alignment strategy. Align returns the alignment and whether the
+// strategy is confident in it; a strategy that cannot align a word may still return a
+// best-effort result with ok == false.
+type Aligner interface {
+	Align(in Input) (res Result, ok bool)
+}
+
+// Chain tries each aligner in order and returns the first confident result. If none is
+// confident it returns the first non-empty best-effort result.
+type Chain []Aligner
+
+// Align implements Aligner.
+func (c Chain) Align(in Input) (Result, bool) {
+	var fallback Result
+	haveFallback := false
+	for _, a := range c {
+		res, ok := a.Align(in)
+		if ok {
+			return res, true
+		}
+		if !haveFallback && len(res.Pairs) > 0 {
+			fallback = res
+			haveFallback = true
+		}
+	}
+	return fallback, false
+}
+
+// String names the strategies in order, e.g. "override,kanjidic", with the state
+// of those that have any; caches use it to tell alignment settings apart.
+func (c Chain) String() string {
+	names := make([]string, len(c))
+	for i, a := range c {
+		names[i] = fmt.Sprint(a)
+	}
+	return strings.Join(names, ",")
+}
+
+// UserOverrides holds user-supplied alignments used by the "override" strategy.
+var UserOverrides = NewOverrides()
+
+// Default returns the standard chain: user overrides, then Kanjidic2 greedy
+// alignment, then numerals with counters, then dictionary-reading-based alignment.
+func Default() Aligner {
+	return Chain{UserOverrides, Kanjidic{}, Numeric{}, Dictionary{}}
+}
+
+// Strategy returns the aligner registered under name: "override", "kanjidic",
+// "numeric" or "dictionary".
+func Strategy(name string) (Aligner, error) {
+	switch strings.TrimSpace(name) {
+	case "override":
+		return UserOverrides, nil
+	case "kanjidic":
+		return Kanjidic{}, nil
+	case "numeric":
+		return Numeric{}, nil
+	case "dictionary":
+		return Dictionary{}, nil
+	}
+	return nil, fmt.Errorf("unknown furigana strategy %q", name)
+}
+
+// NewChain builds a Chain from strategy names, e.g. NewChain("override", "kanjidic").
+func NewChain(names ...string) (Aligner, error) {
+	c := make(Chain, 0, len(names))
+	for _, n := range names {
+		a, err := Strategy(n)
+		if err != nil {
+			return nil, err
+		}
+		c = append(c, a)
+	}
+	return c, nil
+}
+
+// Align runs the default chain over surface and reading.
+func Align(surface, reading string) [][2]string {
+	res, _ := Default().Align(Input{Surface: surface, Reading: reading})
+	return res.Pairs
+}
+
+// FormatBracketsOnly formats furigana so only kanji readings are in brackets, with
+// non-kanji characters outside, e.g. "[み]る". Every kanji pair produces a bracketed
+// block, even if its furigana is empty; other pairs (such as Arabic numerals) are
+// bracketed only when they carry a reading.
+func FormatBracketsOnly(pairs [][2]string) string {
+	out := ""
+	for _, pair := range pairs {
+		if len(pair[0]) == 0 {
+			continue // skip leftover reading segments
+		}
+		first := []rune(pair[0])[0]
+		if IsKanji(first) || pair[1] != "" {
+			out += "[" + pair[1] + "]"
+		} else {
+			out += pair[0]
+		}
+	}
+	return out
+}
+
+// FormatDisplay formats furigana pairs as [kanji|furigana] blocks with plain kana.
+func FormatDisplay(pairs [][2]string) string {
+	out := ""
+	for _, pair := range pairs {
+		if pair[1] != "" && pair[0] != "" {
+			out += "[" + pair[0] + "|" + pair[1] + "]"
+		} else {
+			out += pair[0]
+		}
+	}
+	return out
+}
+
+// ParseSegmentation parses a space-separated segmentation such as "入=いり 見=み る"
+// into pairs. Chunks written surface=reading become ruby pairs; other chunks become
+// one plain pair per character.
+func ParseSegmentation(s string) [][2]string {
+	var out [][2]string
+	for _, chunk := range strings.Fields(s) {
+		if idx := strings.Index(chunk, "="); idx >= 0 {
+			out = append(out, [2]string{chunk[:idx], chunk[idx+1:]})
+			continue
+		}
+		for _, r := range chunk {
+			out = append(out, [2]string{string(r), ""})
+		}
+	}
+	return out
+}
+
+// IsKanji reports whether r is a CJK unified ideograph.
+func IsKanji(r rune) bool {
+	return r >= 0x4E00 && r <= 0x9FFF
+}
+
+// isKana returns true if rune is Hiragana or Katakana
+func isKana(r rune) bool {
+	return (r >= 0x3040 && r <= 0x309F) || (r >= 0x30A0 && r <= 0x30FF)
+}
+
+// KatakanaToHiragana converts katakana to hiragana for furigana display.
+func KatakanaToHiragana(s string) string {
+	runes := []rune(s)
+	for i, r := range runes {
+		if r >= 0x30A1 && r <= 0x30F6 {
+			runes[i] = r - 0x60
+		}
+	}
+	return string(runes)
+}
@@ -0,0 +1,66 @@
+package furigana
+
+import (
+	"testing"
+
+	"japaneseparse/kanji"
+)
+
+func TestFuriganaAlignmentForIriminaiKawa(t *testing.T) {
+	err := kanji.InitKanjidic2("testdata/kanjidic2_sample.xml")
+	if err != nil {
+		t.Fatalf("Failed to initialize Kanjidic2: %v", err)
+	}
+	surface := "入見内川"
+	reading := "イリミナイカワ"
+	res, ok := Default().Align(Input{Surface: surface, Reading: reading})
+	aligned := FormatBracketsOnly(res.Pairs)
+	t.Logf("Furigana alignment for %s (%s): %s", surface, KatakanaToHiragana(reading), aligned)
+	expected := "[いり][み][ない][かわ]"
+	if aligned != expected {
+		t.Errorf("Expected %s, got %s", expected, aligned)
+	}
+	if !ok || res.Jukujikun {
+		t.Errorf("Expected a confident per-kanji alignment, got confident=%v jukujikun=%v", ok, res.Jukujikun)
+	}
+}
+
+func TestJukujikunWordLevelRuby(t *testing.T) {
+	res, ok := Default().Align(Input{Surface: "今日", Reading: "キョウ"})
+	if got := FormatBracketsOnly(res.Pairs); !ok || got != "[きょう]" || !res.Jukujikun {
+		t.Errorf("今日: got %s (confident=%v, jukujikun=%v), want [きょう] flagged as jukujikun", got, ok, res.Jukujikun)
+	}
+}
+
+func TestOverrideTakesPrecedence(t *testing.T) {
+	o := NewOverrides()
+	o.Add("角館", "かくのだて", ParseSegmentation("角=かくの 館=だて"))
+	res, ok := Chain{o, Kanjidic{}, Dictionary{}}.Align(Input{Surface: "角館", Reading: "カクノダテ"})
+	if got := FormatBracketsOnly(res.Pairs); !ok || got != "[かくの][だて]" {
+		t.Errorf("override: got %s (confident=%v), want [かくの][だて]", got, ok)
+	}
+}
+
+func TestKanjidicGeminationAndRendaku(t *testing.T) {
+	if err := kanji.InitKanjidic2("testdata/kanjidic2_sample.xml"); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct{ surface, reading, want string }{
+		{"学校", "がっこう", "[がっ][こう]"},
+		{"日記", "にっき", "[にっ][き]"},
+		{"切手", "きって", "[きっ][て]"},
+		{"発表", "はっぴょう", "[はっ][ぴょう]"},
+		{"出発", "しゅっぱつ", "[しゅっ][ぱつ]"},
+		{"手紙", "てがみ", "[て][がみ]"},
+		{"鼻血", "はなぢ", "[はな][ぢ]"},
+		{"三日月", "みかづき", "[み][か][づき]"},
+		// a geminated form must not swallow the っ of okurigana
+		{"切って", "きって", "[き]って"},
+	}
+	for _, c := range cases {
+		res, ok := Kanjidic{}.Align(Input{Surface: c.surface, Reading: c.reading})
+		if got := FormatBracketsOnly(res.Pairs); !ok || got != c.want {
+			t.Errorf("%s (%s): got %s (confident=%v), want %s", c.surface, c.reading, got, ok, c.want)
+		}
+	}
+}
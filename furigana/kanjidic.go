@@ -0,0 +1,160 @@
+package furigana
+
+import (
+	"strings"
+
+	"japaneseparse/kanji"
+)
+
+// Kanjidic aligns greedily, one kanji at a time, taking the longest Kanjidic2 reading
+// that matches the remaining reading. Non-initial kanji may take the rendaku form of a
+// reading, or its handakuten form after っ or ん (発表 はっぴょう); a kanji followed by
+// another kanji may take a geminated form (学校 がっこう, 切手 きって). It is confident
+// only when every kanji matched a dictionary reading and the whole reading was consumed.
+type Kanjidic struct{}
+
+func (Kanjidic) String() string { return "kanjidic" }
+
+// Align implements Aligner.
+func (Kanjidic) Align(in Input) (Result, bool) {
+	ok := true
+	result := make([][2]string, 0)
+	surfaceRunes := []rune(in.Surface)
+	readingRunes := []rune(KatakanaToHiragana(in.Reading))
+	k := 0
+	for j := 0; j < len(surfaceRunes); j++ {
+		s := surfaceRunes[j]
+		if IsKanji(s) {
+			bestLen := 0
+			for _, v := range readingVariants(s) {
+				if matchAt(readingRunes, k, v) && len([]rune(v)) > bestLen {
+					bestLen = len([]rune(v))
+				}
+				// rendaku match for non-first kanji
+				if j > 0 {
+					if rForm := kanji.RendakuForm(v); matchAt(readingRunes, k, rForm) && len([]rune(rForm)) > bestLen {
+						bestLen = len([]rune(rForm))
+					}
+					if k > 0 && (readingRunes[k-1] == 'っ' || readingRunes[k-1] == 'ん') {
+						if hForm := kanji.HandakuForm(v); matchAt(readingRunes, k, hForm) && len([]rune(hForm)) > bestLen {
+							bestLen = len([]rune(hForm))
+						}
+					}
+				}
+			}
+			if j+1 < len(surfaceRunes) && IsKanji(surfaceRunes[j+1]) {
+				for _, v := range geminatedVariants(s) {
+					if matchAt(readingRunes, k, v) && len([]rune(v)) > bestLen {
+						bestLen = len([]rune(v))
+					}
+				}
+			}
+			if bestLen > 0 {
+				result = append(result, [2]string{string(s), string(readingRunes[k : k+bestLen])})
+				k += bestLen
+				continue
+			}
+			ok = false
+			// If no match, assign remaining reading to the kanji if it's the last kanji
+			isLastKanji := true
+			for jj := j + 1; jj < len(surfaceRunes); jj++ {
+				if IsKanji(surfaceRunes[jj]) {
+					isLastKanji = false
+					break
+				}
+			}
+			if isLastKanji && k < len(readingRunes) {
+				result = append(result, [2]string{string(s), string(readingRunes[k:])})
+				k = len(readingRunes)
+			} else {
+				result = append(result, [2]string{string(s), ""})
+			}
+		} else if isKana(s) {
+			result = append(result, [2]string{string(s), ""})
+			if k < len(readingRunes) && readingRunes[k] == []rune(KatakanaToHiragana(string(s)))[0] {
+				k++
+			} else {
+				ok = false
+			}
+		} else {
+			result = append(result, [2]string{string(s), ""})
+		}
+	}
+	if k < len(readingRunes) {
+		ok = false
+		// If there is leftover reading and no kanji to carry it, append as plain text
+		kanjiLeft := false
+		for _, r := range surfaceRunes {
+			if IsKanji(r) {
+				kanjiLeft = true
+				break
+			}
+		}
+		if !kanjiLeft {
+			result = append(result, [2]string{"", string(readingRunes[k:])})
+		}
+	}
+	return Result{Pairs: result}, ok
+}
+
+// readingVariants returns the normalized Kanjidic2 readings of r: each full reading,
+// its stem before the okurigana dot, and the form without a leading '-' marker.
+func readingVariants(r rune) []string {
+	var variants []string
+	add := func(v string) {
+		if v == "" {
+			return
+		}
+		for _, existing := range variants {
+			if existing == v {
+				return
+			}
+		}
+		variants = append(variants, v)
+	}
+	for _, kr := range kanji.GetKanjiReadings(r) {
+		add(kanji.NormalizeReading(kr))
+		if idx := strings.IndexRune(kr, '.'); idx >= 0 {
+			add(kanji.NormalizeReading(kr[:idx]))
+		}
+		if strings.HasPrefix(kr, "-") {
+			add(kanji.NormalizeReading(strings.TrimPrefix(kr, "-")))
+		}
+	}
+	return variants
+}
+
+// geminatedVariants returns the forms r takes before a consonant it doubles: on readings
+// ending in つ, ち, く or き with that mora replaced by っ (がく → がっ), and the stems
+// of kun readings whose okurigana starts with る, つ or う followed by っ, as in the
+// te form (き.る → きっ).
+func geminatedVariants(r rune) []string {
+	var variants []string
+	for _, kr := range kanji.GetKanjiReadings(r) {
+		stem, okurigana, hasDot := strings.Cut(kr, ".")
+		stem = kanji.NormalizeReading(stem)
+		if stem == "" {
+			continue
+		}
+		okurigana = kanji.NormalizeReading(okurigana)
+		if hasDot {
+			if strings.HasPrefix(okurigana, "る") || strings.HasPrefix(okurigana, "つ") || strings.HasPrefix(okurigana, "う") {
+				variants = append(variants, stem+"っ")
+			}
+			continue
+		}
+		if runes := []rune(stem); len(runes) > 1 && strings.ContainsRune("つちくき", runes[len(runes)-1]) {
+			variants = append(variants, string(runes[:len(runes)-1])+"っ")
+		}
+	}
+	return variants
+}
+
+// matchAt reports whether v occurs in reading at rune offset k.
+func matchAt(reading []rune, k int, v string) bool {
+	vRunes := []rune(v)
+	if len(vRunes) == 0 || k+len(vRunes) > len(reading) {
+		return false
+	}
+	return string(reading[k:k+len(vRunes)]) == v
+}
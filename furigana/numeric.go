@@ -0,0 +1,40 @@
+package furigana
+
+import (
+	"japaneseparse/numerals"
+)
+
+// Numeric aligns numerals, optionally followed by a counter ("283世帯", "8時"), when the
+// reading matches the one generated by package numerals. The numeral gets one ruby and
+// the counter another; irregular whole readings (ひとり, ついたち) get a single ruby over
+// the span.
+type Numeric struct{}
+
+func (Numeric) String() string { return "numeric" }
+
+// Align implements Aligner.
+func (Numeric) Align(in Input) (Result, bool) {
+	num, rest := numerals.SplitNumeralPrefix(in.Surface)
+	if num == "" {
+		return Result{}, false
+	}
+	n, ok := numerals.Parse(num)
+	if !ok {
+		return Result{}, false
+	}
+	r, known := numerals.ReadWithCounter(n, rest)
+	if rest != "" && !known {
+		return Result{}, false
+	}
+	if r.String() != KatakanaToHiragana(in.Reading) {
+		return Result{}, false
+	}
+	if rest != "" && r.Counter == "" {
+		return Result{Pairs: [][2]string{{in.Surface, r.Number}}}, true
+	}
+	pairs := [][2]string{{num, r.Number}}
+	if rest != "" {
+		pairs = append(pairs, [2]string{rest, r.Counter})
+	}
+	return Result{Pairs: pairs}, true
+}
@@ -0,0 +1,101 @@
+package furigana
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Overrides is the user-override strategy: explicit alignments for specific
+// surface/reading pairs, e.g. place names the other strategies get wrong.
+type Overrides struct {
+	mu      sync.RWMutex
+	entries map[string]Result
+}
+
+// NewOverrides returns an empty override set.
+func NewOverrides() *Overrides {
+	return &Overrides{entries: make(map[string]Result)}
+}
+
+func overrideKey(surface, reading string) string {
+	return surface + "\t" + KatakanaToHiragana(reading)
+}
+
+// Add registers pairs as the alignment of surface read as reading. A pair whose
+// surface spans several kanji marks the word as jukujikun.
+func (o *Overrides) Add(surface, reading string, pairs [][2]string) {
+	res := Result{Pairs: pairs}
+	for _, p := range pairs {
+		runes := []rune(p[0])
+		if len(runes) > 1 && IsKanji(runes[0]) {
+			res.Jukujikun = true
+		}
+	}
+	o.mu.Lock()
+	o.entries[overrideKey(surface, reading)] = res
+	o.mu.Unlock()
+}
+
+// Len returns the number of registered overrides.
+func (o *Overrides) Len() int {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return len(o.entries)
+}
+
+// Load reads overrides from a TSV file of surface<TAB>reading<TAB>segmentation lines,
+// using the segmentation format of ParseSegmentation. Blank lines and # comments are
+// skipped.
+func (o *Overrides) Load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		cols := strings.Split(text, "\t")
+		if len(cols) < 3 {
+			return fmt.Errorf("%s:%d: expected surface, reading and segmentation", path, line)
+		}
+		o.Add(cols[0], cols[1], ParseSegmentation(cols[2]))
+	}
+	return sc.Err()
+}
+
+// String returns "override" with a digest of the registered alignments, so two
+// override sets with the same content describe themselves the same way.
+func (o *Overrides) String() string {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	keys := make([]string, 0, len(o.entries))
+	for k := range o.entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s\t%v\n", k, o.entries[k].Pairs)
+	}
+	return fmt.Sprintf("override:%d:%x", len(keys), h.Sum(nil)[:8])
+}
+
+// Align implements Aligner.
+func (o *Overrides) Align(in Input) (Result, bool) {
+	o.mu.RLock()
+	res, ok := o.entries[overrideKey(in.Surface, in.Reading)]
+	o.mu.RUnlock()
+	return res, ok
+}
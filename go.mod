@@ -8,6 +8,7 @@ require (
 	github.com/ikawaha/kagome-dict/ipa v1.2.5
 	github.com/ikawaha/kagome/v2 v2.10.2
 	github.com/yomidevs/jmdict-go v0.0.0-20241008135154-36b8b64ae145
+	golang.org/x/text v0.23.0
 )
 
 require github.com/ikawaha/kagome-dict v1.1.6 // indirect
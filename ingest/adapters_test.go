@@ -0,0 +1,67 @@
+package ingest
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDocumentFromHTMLKeepsRubyAndParagraphs(t *testing.T) {
+	page := `<html><head><title>ニュース</title></head><body><p>今日は<ruby>雨<rt>あめ</rt></ruby>が
+	降った。</p><p>第二段落です。</p></body></html>`
+	doc, err := DocumentFromHTML(strings.NewReader(page), Metadata{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Metadata.Title != "ニュース" || len(doc.Paragraphs) != 2 {
+		t.Fatalf("title %q, %d paragraphs", doc.Metadata.Title, len(doc.Paragraphs))
+	}
+	if got := doc.Paragraphs[0].Sentences[0].Text; got != "今日は雨が降った。" {
+		t.Errorf("first sentence = %q", got)
+	}
+	if len(doc.Ruby) != 1 {
+		t.Fatalf("got %d ruby; want 1", len(doc.Ruby))
+	}
+	r := doc.Ruby[0]
+	if base := string([]rune(doc.Text)[r.Start:r.End]); base != "雨" || r.Reading != "あめ" {
+		t.Errorf("ruby %+v covers %q", r, base)
+	}
+}
+
+func TestDocumentFromEPUBSpineOrder(t *testing.T) {
+	files := map[string]string{
+		"META-INF/container.xml": `<container><rootfiles><rootfile full-path="OEBPS/content.opf"/></rootfiles></container>`,
+		"OEBPS/content.opf": `<package><metadata><dc:title>物語</dc:title><dc:language>ja</dc:language></metadata>
+			<manifest><item id="c1" href="one.xhtml" media-type="application/xhtml+xml"/>
+			<item id="c2" href="two.xhtml" media-type="application/xhtml+xml"/></manifest>
+			<spine><itemref idref="c2"/><itemref idref="c1"/></spine></package>`,
+		"OEBPS/one.xhtml": `<html><body><p>一章です。</p></body></html>`,
+		"OEBPS/two.xhtml": `<html><body><p>序章です。</p></body></html>`,
+	}
+	name := filepath.Join(t.TempDir(), "book.epub")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for n, body := range files {
+		w, _ := zw.Create(n)
+		w.Write([]byte(body))
+	}
+	zw.Close()
+	f.Close()
+
+	doc, err := DocumentFromFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Metadata.Title != "物語" {
+		t.Errorf("title = %q", doc.Metadata.Title)
+	}
+	s := doc.Sentences()
+	if len(s) != 2 || s[0].Text != "序章です。" || s[1].Text != "一章です。" {
+		t.Errorf("sentences out of spine order: %+v", s)
+	}
+}
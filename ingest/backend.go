@@ -0,0 +1,380 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+)
+
+// QueueBackend records sentences passing through the ingest queue so they can outlive
+// the process. IngestChan still delivers sentences to consumers; the backend only
+// decides what is remembered. MemoryBackend, the default, remembers nothing.
+type QueueBackend interface {
+	// Append records s before it is queued.
+	Append(s Sentence) error
+	// Ack marks the oldest unacknowledged sentence with this ID as done. Unknown IDs
+	// are ignored.
+	Ack(id string) error
+	// Fail marks the oldest unacknowledged sentence with this ID as failed, so it is
+	// not replayed either. Backends may keep failed sentences for inspection.
+	Fail(id string, cause error) error
+	// Pending returns the sentences appended but not acknowledged, oldest first.
+	Pending() ([]Sentence, error)
+	Close() error
+}
+
+// MemoryBackend keeps nothing beyond IngestChan: sentences queued when the process
+// dies are lost.
+type MemoryBackend struct{}
+
+func (MemoryBackend) Append(Sentence) error        { return nil }
+func (MemoryBackend) Ack(string) error             { return nil }
+func (MemoryBackend) Fail(string, error) error     { return nil }
+func (MemoryBackend) Pending() ([]Sentence, error) { return nil, nil }
+func (MemoryBackend) Close() error                 { return nil }
+
+var backend QueueBackend = MemoryBackend{}
+
+// SetBackend makes Submit and TrySubmit record sentences in b; nil restores
+// MemoryBackend. The previous backend is not closed. Call it before producers start.
+func SetBackend(b QueueBackend) {
+	if b == nil {
+		b = MemoryBackend{}
+	}
+	queueMu.Lock()
+	defer queueMu.Unlock()
+	backend = b
+}
+
+func currentBackend() QueueBackend {
+	queueMu.RLock()
+	defer queueMu.RUnlock()
+	return backend
+}
+
+// Record appends s to the current backend without queueing it, for consumers such as
+// the pipeline that take sentences directly instead of through IngestChan.
+func Record(s Sentence) error {
+	return currentBackend().Append(s)
+}
+
+// Ack marks the sentence with id as fully analyzed in the current backend, so it is
+// not replayed after a restart.
+func Ack(id string) error {
+	return currentBackend().Ack(id)
+}
+
+// Fail marks the sentence with id as failed in the current backend, so it is not
+// replayed after a restart.
+func Fail(id string, cause error) error {
+	return currentBackend().Fail(id, cause)
+}
+
+// Pending returns the sentences of the current backend that were never acknowledged.
+func Pending() ([]Sentence, error) {
+	return currentBackend().Pending()
+}
+
+// Replay queues the backend's pending sentences again, without recording them twice.
+// Call it once at startup, after consumers are running: it blocks while the queue is
+// full until ctx is done. It returns the number of sentences queued.
+func Replay(ctx context.Context) (int, error) {
+	pending, err := Pending()
+	if err != nil {
+		return 0, err
+	}
+	for i, s := range pending {
+		if err := enqueue(ctx, s); err != nil {
+			return i, err
+		}
+	}
+	return len(pending), nil
+}
+
+// fileRecord is one line of a FileBackend log.
+type fileRecord struct {
+	Op       string    `json:"op"` // "put", "ack" or "fail"
+	ID       string    `json:"id,omitempty"`
+	Sentence *Sentence `json:"sentence,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// compactAfter is the number of records a FileBackend appends before it considers
+// rewriting its log.
+const compactAfter = 1000
+
+// FileBackend keeps an append-only log of JSON lines: a "put" record for every queued
+// sentence and an "ack" or "fail" record once it is done. Each record is written with
+// a single write so a crash loses at most a partial last line, which is skipped on
+// open. Only unacknowledged sentences are held in memory, and the log is compacted
+// once most of its records are settled. Failed sentences are also appended to
+// <path>.failed with their error.
+type FileBackend struct {
+	mu      sync.Mutex
+	path    string
+	f       *os.File
+	next    uint64              // sequence number of the next put
+	entries map[uint64]Sentence // unacknowledged sentences by sequence number
+	byID    map[string][]uint64 // unacknowledged sequence numbers per ID, oldest first
+	records int                 // records in the log
+}
+
+// OpenFileBackend opens or creates the log at path and reads the sentences that were
+// never acknowledged. The log is compacted to just those sentences before new records
+// are appended.
+func OpenFileBackend(path string) (*FileBackend, error) {
+	b := &FileBackend{path: path, entries: make(map[uint64]Sentence), byID: make(map[string][]uint64)}
+	if f, err := os.Open(path); err == nil {
+		err = b.load(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read queue log %s: %w", path, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err := b.compact(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// load replays the records of an existing log.
+func (b *FileBackend) load(r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var bad error
+	for line := 1; sc.Scan(); line++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		if bad != nil {
+			// a damaged record followed by more records is not a torn last write
+			return bad
+		}
+		var rec fileRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			bad = fmt.Errorf("line %d: %w", line, err)
+			continue
+		}
+		switch rec.Op {
+		case "put":
+			if rec.Sentence != nil {
+				b.add(*rec.Sentence)
+			}
+		case "ack", "fail":
+			b.settle(rec.ID)
+		default:
+			bad = fmt.Errorf("line %d: unknown op %q", line, rec.Op)
+		}
+	}
+	return sc.Err()
+}
+
+func (b *FileBackend) add(s Sentence) {
+	b.byID[s.ID] = append(b.byID[s.ID], b.next)
+	b.entries[b.next] = s
+	b.next++
+}
+
+// settle forgets the oldest unacknowledged sentence with id and returns it.
+func (b *FileBackend) settle(id string) (Sentence, bool) {
+	seqs := b.byID[id]
+	if len(seqs) == 0 {
+		return Sentence{}, false
+	}
+	s := b.entries[seqs[0]]
+	delete(b.entries, seqs[0])
+	if len(seqs) == 1 {
+		delete(b.byID, id)
+	} else {
+		b.byID[id] = seqs[1:]
+	}
+	return s, true
+}
+
+func (b *FileBackend) pending() []Sentence {
+	seqs := make([]uint64, 0, len(b.entries))
+	for seq := range b.entries {
+		seqs = append(seqs, seq)
+	}
+	slices.Sort(seqs)
+	out := make([]Sentence, len(seqs))
+	for i, seq := range seqs {
+		out[i] = b.entries[seq]
+	}
+	return out
+}
+
+// compact rewrites the log with only the pending sentences, replacing it atomically,
+// and reopens it for appending.
+func (b *FileBackend) compact() (err error) {
+	defer func() {
+		if b.f == nil {
+			// keep appending to whichever log is in place, even after a failure
+			var oerr error
+			if b.f, oerr = os.OpenFile(b.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644); err == nil {
+				err = oerr
+			}
+		}
+	}()
+	if b.f != nil {
+		if err := b.f.Close(); err != nil {
+			return err
+		}
+		b.f = nil
+	}
+	pending := b.pending()
+	tmp := b.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for i := range pending {
+		line, err := json.Marshal(fileRecord{Op: "put", Sentence: &pending[i]})
+		if err != nil {
+			f.Close()
+			return err
+		}
+		w.Write(append(line, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, b.path); err != nil {
+		return err
+	}
+	b.next, b.entries, b.byID = 0, make(map[uint64]Sentence), make(map[string][]uint64)
+	for _, s := range pending {
+		b.add(s)
+	}
+	b.records = len(pending)
+	return nil
+}
+
+// maybeCompact compacts the log once it holds many settled records.
+func (b *FileBackend) maybeCompact() error {
+	if b.records < compactAfter || b.records < 4*len(b.entries) {
+		return nil
+	}
+	return b.compact()
+}
+
+func (b *FileBackend) write(rec fileRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if b.f == nil {
+		return errors.New("queue log closed")
+	}
+	if _, err := b.f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	b.records++
+	return nil
+}
+
+// Append writes a put record for s.
+func (b *FileBackend) Append(s Sentence) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.write(fileRecord{Op: "put", Sentence: &s}); err != nil {
+		return err
+	}
+	b.add(s)
+	return nil
+}
+
+// Ack writes an ack record if id has an unacknowledged sentence.
+func (b *FileBackend) Ack(id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.byID[id]) == 0 {
+		return nil
+	}
+	if err := b.write(fileRecord{Op: "ack", ID: id}); err != nil {
+		return err
+	}
+	b.settle(id)
+	return b.maybeCompact()
+}
+
+// Fail writes a fail record if id has an unacknowledged sentence and appends the
+// sentence and cause to the dead-letter file <path>.failed.
+func (b *FileBackend) Fail(id string, cause error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.byID[id]) == 0 {
+		return nil
+	}
+	msg := ""
+	if cause != nil {
+		msg = cause.Error()
+	}
+	if err := b.write(fileRecord{Op: "fail", ID: id, Error: msg}); err != nil {
+		return err
+	}
+	s, _ := b.settle(id)
+	line, err := json.Marshal(fileRecord{Op: "fail", ID: id, Sentence: &s, Error: msg})
+	if err != nil {
+		return err
+	}
+	dead, err := os.OpenFile(b.path+".failed", os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = dead.Write(append(line, '\n'))
+	if cerr := dead.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return b.maybeCompact()
+}
+
+// Pending returns the unacknowledged sentences, oldest first.
+func (b *FileBackend) Pending() ([]Sentence, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pending(), nil
+}
+
+// Sync flushes the log to stable storage, for durability beyond a process crash.
+func (b *FileBackend) Sync() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.f == nil {
+		return nil
+	}
+	return b.f.Sync()
+}
+
+// Close closes the log file.
+func (b *FileBackend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.f == nil {
+		return nil
+	}
+	err := b.f.Close()
+	b.f = nil
+	return err
+}
@@ -0,0 +1,89 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileBackendReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.log")
+	b, err := OpenFileBackend(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetBackend(b)
+	defer SetBackend(nil)
+
+	ctx := context.Background()
+	for _, id := range []string{"a", "b", "c"} {
+		if err := Submit(ctx, Sentence{ID: id, Text: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		<-Queue()
+	}
+	if err := Ack("b"); err != nil {
+		t.Fatal(err)
+	}
+	b.Close()
+
+	// simulate a crash in the middle of writing a record
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"op":"ack","id":"a`)
+	f.Close()
+
+	b, err = OpenFileBackend(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	SetBackend(b)
+	n, err := Replay(ctx)
+	if err != nil || n != 2 {
+		t.Fatalf("Replay = %d, %v; want 2 sentences", n, err)
+	}
+	for _, want := range []string{"a", "c"} {
+		if s := <-Queue(); s.ID != want {
+			t.Errorf("replayed %q, want %q", s.ID, want)
+		}
+	}
+	// replayed sentences are not recorded twice
+	if pending, _ := b.Pending(); len(pending) != 2 {
+		t.Errorf("pending after replay = %d, want 2", len(pending))
+	}
+}
+
+func TestFileBackendCompacts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.log")
+	b, err := OpenFileBackend(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	for i := 0; i < 3*compactAfter; i++ {
+		id := fmt.Sprint(i)
+		b.Append(Sentence{ID: id, Text: id})
+		if i != 7 {
+			b.Ack(id)
+		}
+	}
+	if len(b.entries) != 1 || b.records >= compactAfter {
+		t.Errorf("after settling: %d entries, %d records in the log", len(b.entries), b.records)
+	}
+	b.Close()
+	b, err = OpenFileBackend(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if pending, _ := b.Pending(); len(pending) != 1 || pending[0].ID != "7" {
+		t.Errorf("pending after reopen = %v", pending)
+	}
+}
@@ -0,0 +1,133 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Metadata describes where a document came from.
+type Metadata struct {
+	Title    string `json:"title,omitempty"`
+	Source   string `json:"source,omitempty"` // file path, URL or other origin
+	Language string `json:"language,omitempty"`
+}
+
+// Paragraph is a run of sentences separated from the next one by a blank line. Start
+// and End are rune offsets in the document text.
+type Paragraph struct {
+	Index     int        `json:"index"`
+	Start     int        `json:"start"`
+	End       int        `json:"end"`
+	Sentences []Sentence `json:"sentences"`
+}
+
+// Document is an ingested text with its metadata, paragraphs and sentences.
+type Document struct {
+	ID         string      `json:"id"`
+	Metadata   Metadata    `json:"metadata"`
+	Text       string      `json:"text"` // raw text as received
+	Paragraphs []Paragraph `json:"paragraphs"`
+	Ruby       []Ruby      `json:"ruby,omitempty"` // readings from source markup, offsets in Text
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+// Sentences returns the sentences of d in document order.
+func (d Document) Sentences() []Sentence {
+	var out []Sentence
+	for _, p := range d.Paragraphs {
+		out = append(out, p.Sentences...)
+	}
+	return out
+}
+
+// NewDocument normalizes text, splits it into paragraphs at blank lines and each
+// paragraph into sentences. Every sentence records its document, paragraph, index in
+// the document and rune span in text. Language defaults to "ja".
+func NewDocument(text string, meta Metadata) (Document, error) {
+	if meta.Language == "" {
+		meta.Language = "ja"
+	}
+	doc := Document{
+		ID:        newID(text),
+		Metadata:  meta,
+		Text:      text,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	normalized, offsets := Normalize(text, Normalization)
+	raw := []rune(text)
+	runes := []rune(normalized)
+	index := 0
+	for _, ps := range splitParagraphs(runes) {
+		p := Paragraph{Index: len(doc.Paragraphs), Start: offsets[ps.Start], End: offsets[ps.End]}
+		for _, sp := range SplitSentences(string(runes[ps.Start:ps.End])) {
+			sp.Start += ps.Start
+			sp.End += ps.Start
+			start, end := offsets[sp.Start], offsets[sp.End]
+			local := make([]int, sp.End-sp.Start+1)
+			for i := range local {
+				local[i] = offsets[sp.Start+i] - start
+			}
+			p.Sentences = append(p.Sentences, Sentence{
+				ID:         newID(string(runes[sp.Start:sp.End])),
+				Text:       string(runes[sp.Start:sp.End]),
+				Raw:        string(raw[start:end]),
+				Offsets:    local,
+				Start:      start,
+				End:        end,
+				DocumentID: doc.ID,
+				Paragraph:  p.Index,
+				Index:      index,
+				CreatedAt:  doc.CreatedAt,
+			})
+			index++
+		}
+		if len(p.Sentences) > 0 {
+			doc.Paragraphs = append(doc.Paragraphs, p)
+		}
+	}
+	if index == 0 {
+		return Document{}, errors.New("empty document")
+	}
+	return doc, nil
+}
+
+// IngestDocument builds a Document from text and submits its sentences to IngestChan
+// in order, blocking while the queue is full until ctx is done.
+func IngestDocument(ctx context.Context, text string, meta Metadata) (Document, error) {
+	doc, err := NewDocument(text, meta)
+	if err != nil {
+		return Document{}, err
+	}
+	return doc, SubmitAll(ctx, doc.Sentences())
+}
+
+// splitParagraphs splits runes at blank lines (lines holding only whitespace).
+func splitParagraphs(runes []rune) []Span {
+	var spans []Span
+	start := 0
+	lineStart := 0
+	blank := true
+	for i, r := range runes {
+		if r == '\n' {
+			if blank && strings.TrimSpace(string(runes[start:lineStart])) != "" {
+				spans = append(spans, Span{Start: start, End: lineStart})
+			}
+			if blank {
+				start = i + 1
+			}
+			lineStart, blank = i+1, true
+			continue
+		}
+		if !unicode.IsSpace(r) {
+			blank = false
+		}
+	}
+	if strings.TrimSpace(string(runes[start:])) != "" {
+		spans = append(spans, Span{Start: start, End: len(runes)})
+	}
+	return spans
+}
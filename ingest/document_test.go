@@ -0,0 +1,25 @@
+package ingest
+
+import "testing"
+
+func TestNewDocumentProvenance(t *testing.T) {
+	text := "雨が降った。傘を持つ。\n\n　ＯＫです。"
+	doc, err := NewDocument(text, Metadata{Title: "t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Paragraphs) != 2 {
+		t.Fatalf("got %d paragraphs; want 2", len(doc.Paragraphs))
+	}
+	sentences := doc.Sentences()
+	if len(sentences) != 3 {
+		t.Fatalf("got %d sentences; want 3", len(sentences))
+	}
+	last := sentences[2]
+	if last.Text != "OKです。" || last.Paragraph != 1 || last.Index != 2 || last.DocumentID != doc.ID {
+		t.Errorf("unexpected last sentence %+v", last)
+	}
+	if got := string([]rune(text)[last.Start:last.End]); got != "ＯＫです。" {
+		t.Errorf("raw span of last sentence = %q; want ＯＫです。", got)
+	}
+}
@@ -0,0 +1,232 @@
+package ingest
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Encoding names a text encoding of byte input.
+type Encoding string
+
+const (
+	EncodingAuto      Encoding = "" // detect from BOM and content
+	EncodingUTF8      Encoding = "utf-8"
+	EncodingUTF16LE   Encoding = "utf-16le"
+	EncodingUTF16BE   Encoding = "utf-16be"
+	EncodingShiftJIS  Encoding = "shift_jis" // decoded with the CP932 (Windows-31J) tables
+	EncodingCP932     Encoding = "cp932"
+	EncodingEUCJP     Encoding = "euc-jp"
+	EncodingISO2022JP Encoding = "iso-2022-jp"
+)
+
+// InputEncoding is the encoding the file adapters assume for byte input. EncodingAuto
+// detects it per file.
+var InputEncoding = EncodingAuto
+
+// encodingLabels maps the spellings found in flags, HTML and XML declarations.
+var encodingLabels = map[string]Encoding{
+	"": EncodingAuto, "auto": EncodingAuto,
+	"utf-8": EncodingUTF8, "utf8": EncodingUTF8,
+	"utf-16le": EncodingUTF16LE, "utf-16be": EncodingUTF16BE,
+	"shift_jis": EncodingShiftJIS, "shift-jis": EncodingShiftJIS, "sjis": EncodingShiftJIS, "x-sjis": EncodingShiftJIS,
+	"cp932": EncodingCP932, "ms932": EncodingCP932, "windows-31j": EncodingCP932, "ms_kanji": EncodingCP932,
+	"euc-jp": EncodingEUCJP, "eucjp": EncodingEUCJP, "euc_jp": EncodingEUCJP, "x-euc-jp": EncodingEUCJP,
+	"iso-2022-jp": EncodingISO2022JP, "iso2022jp": EncodingISO2022JP, "jis": EncodingISO2022JP,
+}
+
+// ParseEncoding resolves an encoding label such as "sjis", "Windows-31J" or "EUC-JP".
+func ParseEncoding(label string) (Encoding, error) {
+	if e, ok := encodingLabels[strings.ToLower(strings.TrimSpace(label))]; ok {
+		return e, nil
+	}
+	return "", fmt.Errorf("unsupported encoding %q", label)
+}
+
+// codec returns the x/text implementation of e. Shift_JIS and CP932 share the CP932
+// tables: files labelled Shift_JIS almost always use the Windows vendor extensions
+// (①, ㈱, NEC and IBM kanji), and the CP932 mappings are a superset.
+func (e Encoding) codec() encoding.Encoding {
+	switch e {
+	case EncodingUTF16LE:
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	case EncodingUTF16BE:
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+	case EncodingShiftJIS, EncodingCP932:
+		return japanese.ShiftJIS
+	case EncodingEUCJP:
+		return japanese.EUCJP
+	case EncodingISO2022JP:
+		return japanese.ISO2022JP
+	}
+	return encoding.Nop
+}
+
+var boms = []struct {
+	bom []byte
+	enc Encoding
+}{
+	{[]byte{0xEF, 0xBB, 0xBF}, EncodingUTF8},
+	{[]byte{0xFF, 0xFE}, EncodingUTF16LE},
+	{[]byte{0xFE, 0xFF}, EncodingUTF16BE},
+}
+
+// DetectEncoding guesses the encoding of b: a byte order mark wins, then valid UTF-8,
+// then ISO-2022-JP escape sequences, and finally whichever of CP932 and EUC-JP decodes
+// b with fewer invalid sequences and more kana.
+func DetectEncoding(b []byte) Encoding {
+	for _, m := range boms {
+		if bytes.HasPrefix(b, m.bom) {
+			return m.enc
+		}
+	}
+	if utf8.Valid(b) {
+		if bytes.Contains(b, []byte("\x1b$B")) || bytes.Contains(b, []byte("\x1b$@")) {
+			return EncodingISO2022JP // 7-bit, so it is also valid UTF-8
+		}
+		return EncodingUTF8
+	}
+	best, bestScore := EncodingCP932, 0
+	for i, e := range []Encoding{EncodingCP932, EncodingEUCJP} {
+		s, err := e.codec().NewDecoder().Bytes(b)
+		if err != nil {
+			continue
+		}
+		if score := decodeScore(string(s)); i == 0 || score > bestScore {
+			best, bestScore = e, score
+		}
+	}
+	return best
+}
+
+// decodeScore rates a trial decoding: kana and common punctuation count for it,
+// replacement characters and half-width katakana (typical of a wrong guess) against it.
+func decodeScore(s string) int {
+	score := 0
+	for _, r := range s {
+		switch {
+		case r == utf8.RuneError:
+			score -= 10
+		case r >= 0x3041 && r <= 0x30FF, r == '。', r == '、':
+			score += 2
+		case r >= 0xFF61 && r <= 0xFF9F:
+			score--
+		case r >= 0x4E00 && r <= 0x9FFF:
+			score++
+		}
+	}
+	return score
+}
+
+// Decode converts b to a UTF-8 string. With EncodingAuto the encoding is detected; the
+// encoding actually used is returned. A byte order mark is removed.
+func Decode(b []byte, enc Encoding) (string, Encoding, error) {
+	if enc == EncodingAuto {
+		enc = DetectEncoding(b)
+	}
+	for _, m := range boms {
+		if m.enc == enc && bytes.HasPrefix(b, m.bom) {
+			b = b[len(m.bom):]
+			break
+		}
+	}
+	if enc == EncodingUTF8 {
+		return string(b), enc, nil
+	}
+	out, err := enc.codec().NewDecoder().Bytes(b)
+	if err != nil {
+		return "", enc, fmt.Errorf("decode %s: %w", enc, err)
+	}
+	return string(out), enc, nil
+}
+
+// charsetRe finds a charset declared in HTML (<meta charset>, http-equiv) or an XML
+// declaration near the top of a file.
+var charsetRe = regexp.MustCompile(`(?i)(?:charset|encoding)\s*=\s*["']?([A-Za-z0-9_\-]+)`)
+
+// DeclaredEncoding returns the encoding declared in the first kilobyte of an HTML or
+// XML document, or EncodingAuto if there is none or it is not recognised.
+func DeclaredEncoding(b []byte) Encoding {
+	if len(b) > 1024 {
+		b = b[:1024]
+	}
+	m := charsetRe.FindSubmatch(b)
+	if m == nil {
+		return EncodingAuto
+	}
+	e, err := ParseEncoding(string(m[1]))
+	if err != nil {
+		return EncodingAuto
+	}
+	return e
+}
+
+// Unencodable is a character that the target encoding cannot represent.
+type Unencodable struct {
+	Rune   rune `json:"rune"`
+	Line   int  `json:"line"`   // 1-based
+	Column int  `json:"column"` // 1-based, in runes
+}
+
+func (u Unencodable) String() string {
+	return fmt.Sprintf("%d:%d: %q (%U)", u.Line, u.Column, u.Rune, u.Rune)
+}
+
+// cp932Fold maps JIS X 0208 code points, which ingest normalization and other tools
+// produce, to the CP932 code points used for the same Shift_JIS bytes.
+var cp932Fold = map[rune]rune{
+	'〜': '～', // wave dash, 0x8160
+	'‖': '∥', // double vertical line, 0x8161
+	'−': '－', // minus sign, 0x817C
+	'¢': '￠', // 0x8191
+	'£': '￡', // 0x8192
+	'¬': '￢', // 0x81CA
+	'—': '―', // em dash, 0x815C
+}
+
+// Encode converts s to enc. Characters the encoding cannot represent are replaced with
+// '?' and reported. For Shift_JIS, CP932 and EUC-JP the JIS-style code points of the
+// wave dash and similar characters are first folded to their CP932 forms.
+func Encode(s string, enc Encoding) ([]byte, []Unencodable, error) {
+	switch enc {
+	case EncodingAuto, EncodingUTF8:
+		return []byte(s), nil, nil
+	}
+	e := enc.codec().NewEncoder()
+	var out bytes.Buffer
+	var bad []Unencodable
+	line, col := 1, 0
+	for _, r := range s {
+		col++
+		c := r
+		if f, ok := cp932Fold[r]; ok && enc != EncodingUTF16LE && enc != EncodingUTF16BE {
+			c = f
+		}
+		b, err := e.Bytes([]byte(string(c)))
+		if err != nil {
+			bad = append(bad, Unencodable{Rune: r, Line: line, Column: col})
+			b = []byte("?")
+		}
+		out.Write(b)
+		if r == '\n' {
+			line, col = line+1, 0
+		}
+	}
+	if enc == EncodingISO2022JP {
+		// per-rune encoding leaves every character in its own escape run; decode and
+		// re-encode in one pass so the output switches character sets only when needed
+		text, err := enc.codec().NewDecoder().Bytes(out.Bytes())
+		if err != nil {
+			return nil, bad, err
+		}
+		b, err := enc.codec().NewEncoder().Bytes(text)
+		return b, bad, err
+	}
+	return out.Bytes(), bad, nil
+}
@@ -0,0 +1,32 @@
+package ingest
+
+import "testing"
+
+func TestDetectAndRoundTripLegacyEncodings(t *testing.T) {
+	text := "今日は雨〜①髙橋さん。"
+	for _, enc := range []Encoding{EncodingCP932, EncodingEUCJP, EncodingISO2022JP} {
+		b, bad, err := Encode(text, enc)
+		if err != nil {
+			t.Fatalf("%s: %v", enc, err)
+		}
+		if enc == EncodingCP932 && len(bad) != 0 {
+			t.Errorf("%s: unexpected unencodable %v", enc, bad)
+		}
+		if got := DetectEncoding(b); got != enc {
+			t.Errorf("DetectEncoding(%s bytes) = %s", enc, got)
+		}
+		got, _, err := Decode(b, EncodingAuto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the wave dash comes back in its CP932 form, which Normalize folds again
+		if norm, _ := Normalize(got, DefaultNormalization); enc == EncodingCP932 && norm != "今日は雨〜1髙橋さん。" {
+			t.Errorf("%s round trip = %q", enc, norm)
+		}
+	}
+
+	_, bad, _ := Encode("絵文字😀\nです", EncodingShiftJIS)
+	if len(bad) != 1 || bad[0].Rune != '😀' || bad[0].Line != 1 || bad[0].Column != 4 {
+		t.Errorf("unencodable = %v", bad)
+	}
+}
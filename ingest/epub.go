@@ -0,0 +1,140 @@
+package ingest
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// epubContainer is META-INF/container.xml, which points at the package document.
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+// epubPackage is the OPF package document: metadata, manifest and reading order.
+type epubPackage struct {
+	Title    []string `xml:"metadata>title"`
+	Language []string `xml:"metadata>language"`
+	Manifest []struct {
+		ID        string `xml:"id,attr"`
+		Href      string `xml:"href,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef  string `xml:"idref,attr"`
+		Linear string `xml:"linear,attr"`
+	} `xml:"spine>itemref"`
+}
+
+// DocumentFromEPUB reads an EPUB file and builds one Document from its XHTML content
+// documents in spine order. Title and language come from the OPF metadata unless set in
+// meta.
+func DocumentFromEPUB(filename string, meta Metadata) (Document, error) {
+	zr, err := zip.OpenReader(filename)
+	if err != nil {
+		return Document{}, err
+	}
+	defer zr.Close()
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var container epubContainer
+	if err := decodeZipXML(files, "META-INF/container.xml", &container); err != nil {
+		return Document{}, err
+	}
+	if len(container.Rootfiles) == 0 {
+		return Document{}, fmt.Errorf("%s: no rootfile in container.xml", filename)
+	}
+	opfPath := container.Rootfiles[0].FullPath
+	var pkg epubPackage
+	if err := decodeZipXML(files, opfPath, &pkg); err != nil {
+		return Document{}, err
+	}
+	if meta.Title == "" && len(pkg.Title) > 0 {
+		meta.Title = strings.TrimSpace(pkg.Title[0])
+	}
+	if meta.Language == "" && len(pkg.Language) > 0 {
+		meta.Language = strings.TrimSpace(pkg.Language[0])
+	}
+	if meta.Source == "" {
+		meta.Source = filename
+	}
+
+	hrefs := make(map[string]string, len(pkg.Manifest))
+	for _, item := range pkg.Manifest {
+		if strings.Contains(item.MediaType, "html") {
+			hrefs[item.ID] = path.Join(path.Dir(opfPath), item.Href)
+		}
+	}
+
+	// concatenate chapters as paragraphs, shifting ruby offsets as we go
+	var parts []string
+	var ruby []Ruby
+	offset := 0
+	for _, ref := range pkg.Spine {
+		name, ok := hrefs[ref.IDRef]
+		if !ok || ref.Linear == "no" {
+			continue
+		}
+		f, ok := files[name]
+		if !ok {
+			return Document{}, fmt.Errorf("%s: spine item %s missing", filename, name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return Document{}, err
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return Document{}, err
+		}
+		page, err := DecodeHTML(b)
+		if err != nil {
+			return Document{}, fmt.Errorf("%s: %s: %w", filename, name, err)
+		}
+		text, rb, _, err := ParseHTML(strings.NewReader(page))
+		if err != nil {
+			continue // cover pages and image-only chapters have no text
+		}
+		if len(parts) > 0 {
+			offset += 2 // "\n\n" between chapters
+		}
+		for _, r := range rb {
+			r.Start += offset
+			r.End += offset
+			ruby = append(ruby, r)
+		}
+		parts = append(parts, text)
+		offset += len([]rune(text))
+	}
+
+	doc, err := NewDocument(strings.Join(parts, "\n\n"), meta)
+	if err != nil {
+		return Document{}, fmt.Errorf("%s: %w", filename, err)
+	}
+	doc.Ruby = ruby
+	return doc, nil
+}
+
+// decodeZipXML decodes the XML file name from an archive into v.
+func decodeZipXML(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("epub: %s not found", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
@@ -0,0 +1,59 @@
+package ingest
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DocumentFromText builds a Document from plain text read from r, decoded with
+// InputEncoding (detected by default).
+func DocumentFromText(r io.Reader, meta Metadata) (Document, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return Document{}, err
+	}
+	text, _, err := Decode(b, InputEncoding)
+	if err != nil {
+		return Document{}, err
+	}
+	return NewDocument(text, meta)
+}
+
+// DocumentFromFile picks an adapter by file extension: .epub, .html/.htm/.xhtml, or
+// plain text for anything else. The name "-" reads plain text from stdin.
+func DocumentFromFile(filename string) (Document, error) {
+	meta := Metadata{Source: filename}
+	if filename == "-" {
+		meta.Source = "stdin"
+		return DocumentFromText(os.Stdin, meta)
+	}
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext == ".epub" {
+		return DocumentFromEPUB(filename, meta)
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return Document{}, err
+	}
+	defer f.Close()
+	switch ext {
+	case ".html", ".htm", ".xhtml":
+		return DocumentFromHTML(f, meta)
+	}
+	meta.Title = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	return DocumentFromText(f, meta)
+}
+
+// IngestFile builds a Document from a file (see DocumentFromFile) and submits its
+// sentences to IngestChan in order, blocking while the queue is full until ctx is done.
+func IngestFile(ctx context.Context, filename string) (Document, error) {
+	doc, err := DocumentFromFile(filename)
+	if err != nil {
+		return Document{}, err
+	}
+	return doc, SubmitAll(ctx, doc.Sentences())
+}
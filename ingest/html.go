@@ -0,0 +1,219 @@
+package ingest
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Ruby is an author-supplied reading from <ruby> markup. Start and End are rune
+// offsets of Base in the document text.
+type Ruby struct {
+	Base    string `json:"base"`
+	Reading string `json:"reading"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+}
+
+// blockElements end a paragraph.
+var blockElements = map[string]bool{
+	"p": true, "div": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"li": true, "blockquote": true, "section": true, "article": true, "tr": true, "dd": true, "dt": true,
+	"pre": true, "table": true, "ul": true, "ol": true, "body": true,
+}
+
+// skippedElements hold no readable text.
+var skippedElements = map[string]bool{"script": true, "style": true, "head": true, "rp": true, "noscript": true}
+
+// htmlText accumulates the text of an HTML document.
+type htmlText struct {
+	paragraphs []string
+	cur        []rune
+	ruby       []Ruby
+	pending    []Ruby // ruby of the current paragraph, offsets relative to cur
+	title      string
+}
+
+// ParseHTML extracts readable text from HTML or XHTML. Block elements become
+// paragraphs separated by blank lines, <br> becomes a line break, and <ruby> markup is
+// reduced to its base text with the readings returned separately. The <title> is
+// returned as well. r must be UTF-8 (see DecodeHTML); a declared charset is ignored.
+func ParseHTML(r io.Reader) (text string, ruby []Ruby, title string, err error) {
+	d := xml.NewDecoder(r)
+	d.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	var h htmlText
+	skip := 0
+	inTitle, inRuby, inRT := false, false, false
+	var rubyBase, rubyReading []rune
+	rubyStart := 0
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			switch {
+			case skippedElements[name]:
+				skip++
+			case name == "title":
+				inTitle = true
+			case name == "br":
+				h.cur = append(h.cur, '\n')
+			case name == "ruby":
+				inRuby, rubyBase, rubyReading = true, nil, nil
+				rubyStart = len(h.cur)
+			case name == "rt":
+				inRT = true
+			case blockElements[name]:
+				h.flush()
+			}
+		case xml.EndElement:
+			name := strings.ToLower(t.Name.Local)
+			switch {
+			case skippedElements[name]:
+				if skip > 0 {
+					skip--
+				}
+			case name == "title":
+				inTitle = false
+			case name == "rt":
+				inRT = false
+			case name == "ruby":
+				inRuby = false
+				if len(rubyBase) > 0 && len(rubyReading) > 0 {
+					h.pending = append(h.pending, Ruby{
+						Base:    string(rubyBase),
+						Reading: string(rubyReading),
+						Start:   rubyStart,
+						End:     rubyStart + len(rubyBase),
+					})
+				}
+			case blockElements[name]:
+				h.flush()
+			}
+		case xml.CharData:
+			s := string(t)
+			switch {
+			case inTitle:
+				h.title += strings.TrimSpace(s)
+			case skip > 0:
+			case inRT:
+				rubyReading = append(rubyReading, []rune(strings.TrimSpace(s))...)
+			default:
+				s = collapseSpace(s)
+				if inRuby {
+					rubyBase = append(rubyBase, []rune(s)...)
+				}
+				h.cur = append(h.cur, []rune(s)...)
+			}
+		}
+	}
+	h.flush()
+	text = strings.Join(h.paragraphs, "\n\n")
+	if strings.TrimSpace(text) == "" {
+		return "", nil, h.title, errors.New("no text in HTML")
+	}
+	return text, h.ruby, h.title, nil
+}
+
+// flush closes the current paragraph, placing its ruby at their offsets in the joined
+// text.
+func (h *htmlText) flush() {
+	// trim surrounding whitespace but keep offsets of ruby consistent
+	start, end := 0, len(h.cur)
+	for start < end && unicode.IsSpace(h.cur[start]) {
+		start++
+	}
+	for end > start && unicode.IsSpace(h.cur[end-1]) {
+		end--
+	}
+	if start < end {
+		base := 0
+		for _, p := range h.paragraphs {
+			base += len([]rune(p)) + 2 // paragraphs are joined by "\n\n"
+		}
+		for _, r := range h.pending {
+			if r.Start >= start && r.End <= end {
+				r.Start += base - start
+				r.End += base - start
+				h.ruby = append(h.ruby, r)
+			}
+		}
+		h.paragraphs = append(h.paragraphs, string(h.cur[start:end]))
+	}
+	h.cur, h.pending = h.cur[:0], h.pending[:0]
+}
+
+// collapseSpace folds whitespace from source formatting. Runs that contain a line
+// break disappear between Japanese characters and become one space otherwise; other
+// runs become one space.
+func collapseSpace(s string) string {
+	var sb strings.Builder
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		if !unicode.IsSpace(runes[i]) || runes[i] == '　' {
+			sb.WriteRune(runes[i])
+			continue
+		}
+		j, newline := i, false
+		for j < len(runes) && unicode.IsSpace(runes[j]) && runes[j] != '　' {
+			newline = newline || runes[j] == '\n'
+			j++
+		}
+		prevWide := i > 0 && runes[i-1] > unicode.MaxASCII
+		nextWide := j < len(runes) && runes[j] > unicode.MaxASCII
+		if !(newline && prevWide && nextWide) {
+			sb.WriteRune(' ')
+		}
+		i = j - 1
+	}
+	return sb.String()
+}
+
+// DecodeHTML decodes an HTML or XHTML page to UTF-8 using InputEncoding if set, else
+// the charset the page declares, else detection.
+func DecodeHTML(b []byte) (string, error) {
+	enc := InputEncoding
+	if enc == EncodingAuto {
+		enc = DeclaredEncoding(b)
+	}
+	s, _, err := Decode(b, enc)
+	return s, err
+}
+
+// DocumentFromHTML builds a Document from an HTML page. The page title fills in an
+// empty meta.Title.
+func DocumentFromHTML(r io.Reader, meta Metadata) (Document, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return Document{}, err
+	}
+	page, err := DecodeHTML(b)
+	if err != nil {
+		return Document{}, err
+	}
+	text, ruby, title, err := ParseHTML(strings.NewReader(page))
+	if err != nil {
+		return Document{}, err
+	}
+	if meta.Title == "" {
+		meta.Title = title
+	}
+	doc, err := NewDocument(text, meta)
+	if err != nil {
+		return Document{}, err
+	}
+	doc.Ruby = ruby
+	return doc, nil
+}
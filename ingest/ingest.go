@@ -1,28 +1,66 @@
 package ingest
 
 import (
+	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"strings"
 	"time"
+	"unicode"
 )
 
 // Sentence represents an ingested Japanese sentence and metadata.
 type Sentence struct {
-	ID        string    `json:"id"`
-	Text      string    `json:"text"`
-	CreatedAt time.Time `json:"created_at"`
+	ID      string `json:"id"`
+	Text    string `json:"text"`          // normalized text passed to the tokenizer
+	Raw     string `json:"raw,omitempty"` // input as received, before normalization
+	Offsets []int  `json:"offsets,omitempty"`
+	Start   int    `json:"start,omitempty"` // rune offset of Raw in the parent text
+	End     int    `json:"end,omitempty"`
+	// provenance of sentences that belong to a Document
+	DocumentID string    `json:"document_id,omitempty"`
+	Paragraph  int       `json:"paragraph,omitempty"` // index of the paragraph in the document
+	Index      int       `json:"index,omitempty"`     // index of the sentence in the document
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// OriginalSpan maps a rune span [start, end) of Text back to a rune span of Raw. Without
+// an offset map the span is returned unchanged.
+func (s Sentence) OriginalSpan(start, end int) (int, int) {
+	if len(s.Offsets) == 0 || start < 0 || start > end || end >= len(s.Offsets) {
+		return start, end
+	}
+	return s.Offsets[start], s.Offsets[end]
 }
 
 // IngestChan is a channel where ingested sentences are published for downstream processing.
-// Other packages or goroutines can receive from this channel to process sentences.
+// pipeline.Pipeline.Consume drains it into a pipeline; use Queue to receive and Submit
+// or TrySubmit to send (see queue.go).
 var IngestChan chan Sentence
 
 func init() {
 	// buffered channel to decouple producer and consumers
-	IngestChan = make(chan Sentence, 100)
+	IngestChan = make(chan Sentence, DefaultQueueSize)
+}
+
+// ContentIDs makes sentence and document IDs a hash of their text (see ContentID)
+// instead of random, so the same input gets the same ID on every run.
+var ContentIDs bool
+
+// ContentID returns a short hex ID derived from text.
+func ContentID(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:8])
+}
+
+// newID returns ContentID(content) if ContentIDs is set, else a random ID.
+func newID(content string) string {
+	if ContentIDs {
+		return ContentID(content)
+	}
+	return generateID()
 }
 
 // generateID creates a short random hex id. Falls back to a timestamp string on error.
@@ -34,30 +72,52 @@ func generateID() string {
 	return hex.EncodeToString(b)
 }
 
-// IngestSentence is the ingest entrypoint. It trims the input, validates it, constructs
-// a Sentence object and publishes it to IngestChan asynchronously. It returns the
-// created Sentence or an error if the input was invalid.
-func IngestSentence(text string) (Sentence, error) {
-	trimmed := strings.TrimSpace(text)
-	if trimmed == "" {
+// NewSentence normalizes the input (see Normalization), trims it, validates it and
+// constructs a Sentence without submitting it.
+func NewSentence(text string) (Sentence, error) {
+	normalized, offsets := Normalize(text, Normalization)
+	runes := []rune(normalized)
+	start, end := 0, len(runes)
+	for start < end && unicode.IsSpace(runes[start]) {
+		start++
+	}
+	for end > start && unicode.IsSpace(runes[end-1]) {
+		end--
+	}
+	if start == end {
 		return Sentence{}, errors.New("empty sentence")
 	}
 
-	s := Sentence{
-		ID:        generateID(),
-		Text:      trimmed,
+	return Sentence{
+		ID:        newID(string(runes[start:end])),
+		Text:      string(runes[start:end]),
+		Raw:       text,
+		Offsets:   offsets[start : end+1],
 		CreatedAt: time.Now().UTC(),
-	}
-
-	// publish asynchronously so callers are not blocked
-	go func(sent Sentence) {
-		select {
-		case IngestChan <- sent:
-			// published successfully
-		default:
-			// channel is full; drop silently for now (could log or expand buffer)
-		}
-	}(s)
+	}, nil
+}
 
+// IngestSentence is the ingest entrypoint. It builds a Sentence with NewSentence and
+// submits it to IngestChan without blocking. It returns the created Sentence, or an
+// error if the input was invalid or the queue is full (ErrQueueFull). Something must
+// drain the queue, such as pipeline.Pipeline.Consume; otherwise it fills up.
+func IngestSentence(text string) (Sentence, error) {
+	s, err := NewSentence(text)
+	if err != nil {
+		return Sentence{}, err
+	}
+	if err := TrySubmit(s); err != nil {
+		return s, err
+	}
 	return s, nil
 }
+
+// IngestText ingests a multi-sentence text as an untitled Document, submitting its
+// sentences in order, and returns them.
+func IngestText(ctx context.Context, text string) ([]Sentence, error) {
+	doc, err := IngestDocument(ctx, text, Metadata{})
+	if err != nil {
+		return nil, err
+	}
+	return doc.Sentences(), nil
+}
@@ -0,0 +1,122 @@
+package ingest
+
+import (
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
+)
+
+// NormalizeOptions selects the normalization steps applied to text at ingest.
+type NormalizeOptions struct {
+	NFKC     bool // compatibility composition: ①→1, ㈱→(株), full-width ASCII→ASCII
+	Width    bool // fold full-width ASCII to ASCII and half-width katakana to full-width
+	Dakuten  bool // compose combining and spacing (half-width) dakuten with the kana before them
+	WaveDash bool // unify full-width tilde and swung dash to the wave dash 〜
+}
+
+// DefaultNormalization enables every normalization step.
+var DefaultNormalization = NormalizeOptions{NFKC: true, Width: true, Dakuten: true, WaveDash: true}
+
+// Normalization is the configuration used by IngestSentence.
+var Normalization = DefaultNormalization
+
+// waveDashes are the characters unified to 〜 (U+301C).
+var waveDashes = map[rune]bool{'～': true, '⁓': true}
+
+// Normalize applies opts to text and returns the normalized text together with an
+// offset map: offsets[i] is the rune index in text of the i-th rune of the result, and
+// offsets[len] is the rune length of text, so a normalized span [s, e) maps back to
+// [offsets[s], offsets[e]) in the original.
+func Normalize(text string, opts NormalizeOptions) (string, []int) {
+	runes := []rune(text)
+	src := make([]int, len(runes))
+	for i := range src {
+		src[i] = i
+	}
+
+	if opts.WaveDash {
+		for i, r := range runes {
+			if waveDashes[r] {
+				runes[i] = '〜'
+			}
+		}
+	}
+	if opts.Dakuten {
+		runes = combineSpacingMarks(runes)
+	}
+	if opts.Width {
+		runes, src = mapRunes(runes, src, func(r rune) string { return width.Fold.String(string(r)) })
+	}
+	switch {
+	case opts.NFKC:
+		runes, src = applyForm(norm.NFKC, runes, src)
+	case opts.Dakuten || opts.Width:
+		// width folding turns ｶﾞ into カ + combining dakuten; NFC composes them
+		runes, src = applyForm(norm.NFC, runes, src)
+	}
+
+	offsets := append(src, len([]rune(text)))
+	return string(runes), offsets
+}
+
+// combineSpacingMarks turns the spacing dakuten/handakuten (゛゜) and their half-width
+// forms (ﾞﾟ) into combining marks when they follow kana, so composition can merge
+// them: か゛ → が. Marks that do not follow kana are left alone.
+func combineSpacingMarks(runes []rune) []rune {
+	for i := 1; i < len(runes); i++ {
+		if !isKanaRune(runes[i-1]) {
+			continue
+		}
+		switch runes[i] {
+		case '゛', 'ﾞ':
+			runes[i] = '゙'
+		case '゜', 'ﾟ':
+			runes[i] = '゚'
+		}
+	}
+	return runes
+}
+
+// mapRunes replaces each rune with f(rune), keeping the source index of every rune it
+// produces.
+func mapRunes(runes []rune, src []int, f func(rune) string) ([]rune, []int) {
+	out := make([]rune, 0, len(runes))
+	outSrc := make([]int, 0, len(runes))
+	for i, r := range runes {
+		for _, m := range f(r) {
+			out = append(out, m)
+			outSrc = append(outSrc, src[i])
+		}
+	}
+	return out, outSrc
+}
+
+// applyForm normalizes runes to form segment by segment. Every rune of a normalized
+// segment maps to the source index of the first rune of its input segment.
+func applyForm(form norm.Form, runes []rune, src []int) ([]rune, []int) {
+	s := string(runes)
+	// byteSrc[b] is the source index of the rune starting at byte b
+	byteSrc := make([]int, len(s)+1)
+	b := 0
+	for i, r := range runes {
+		byteSrc[b] = src[i]
+		b += len(string(r))
+	}
+
+	out := make([]rune, 0, len(runes))
+	outSrc := make([]int, 0, len(runes))
+	var it norm.Iter
+	it.InitString(form, s)
+	for !it.Done() {
+		start := it.Pos()
+		for _, r := range string(it.Next()) {
+			out = append(out, r)
+			outSrc = append(outSrc, byteSrc[start])
+		}
+	}
+	return out, outSrc
+}
+
+// isKanaRune reports whether r is hiragana, katakana or half-width katakana.
+func isKanaRune(r rune) bool {
+	return (r >= 0x3041 && r <= 0x309F) || (r >= 0x30A1 && r <= 0x30FF) || (r >= 0xFF66 && r <= 0xFF9D)
+}
@@ -0,0 +1,19 @@
+package ingest
+
+import "testing"
+
+func TestNormalizeOffsets(t *testing.T) {
+	in := "ｶﾞｸｾｲ　ＡＢＣ１２３か゛～"
+	out, offsets := Normalize(in, DefaultNormalization)
+	if want := "ガクセイ ABC123が〜"; out != want {
+		t.Fatalf("Normalize(%q) = %q; want %q", in, out, want)
+	}
+	raw := []rune(in)
+	// ガク covers ｶﾞｸ in the input; が covers か゛
+	if got := string(raw[offsets[0]:offsets[2]]); got != "ｶﾞｸ" {
+		t.Errorf("span of ガク = %q; want ｶﾞｸ", got)
+	}
+	if got := string(raw[offsets[11]:offsets[12]]); got != "か゛" {
+		t.Errorf("span of が = %q; want か゛", got)
+	}
+}
@@ -0,0 +1,137 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultQueueSize is the capacity of IngestChan unless SetQueueSize changes it.
+const DefaultQueueSize = 100
+
+// ErrQueueFull is returned by TrySubmit when IngestChan has no free slot.
+var ErrQueueFull = errors.New("ingest queue full")
+
+// QueueStats reports the state of the ingest queue.
+type QueueStats struct {
+	Queued    int    `json:"queued"`    // sentences waiting in IngestChan
+	Capacity  int    `json:"capacity"`  // size of IngestChan
+	Submitted uint64 `json:"submitted"` // sentences accepted since start
+	Dropped   uint64 `json:"dropped"`   // sentences rejected because the queue was full or the caller gave up
+}
+
+var (
+	queueMu   sync.RWMutex
+	submitted atomic.Uint64
+	dropped   atomic.Uint64
+)
+
+// Queue returns the channel consumers should receive sentences from. Consumers should
+// call it on every receive so a resize by SetQueueSize is picked up.
+func Queue() <-chan Sentence {
+	queueMu.RLock()
+	defer queueMu.RUnlock()
+	return IngestChan
+}
+
+// SetQueueSize replaces IngestChan with a channel of capacity n, moving queued
+// sentences across. Call it before producers and consumers start; sentences that do
+// not fit in the new queue are counted as dropped.
+func SetQueueSize(n int) {
+	if n < 0 {
+		n = 0
+	}
+	queueMu.Lock()
+	defer queueMu.Unlock()
+	next := make(chan Sentence, n)
+	for {
+		select {
+		case s := <-IngestChan:
+			select {
+			case next <- s:
+			default:
+				dropped.Add(1)
+			}
+			continue
+		default:
+		}
+		break
+	}
+	IngestChan = next
+}
+
+// Submit records s in the backend and enqueues it, blocking until there is room or
+// ctx is done.
+func Submit(ctx context.Context, s Sentence) error {
+	queueMu.RLock()
+	b := backend
+	queueMu.RUnlock()
+	if err := b.Append(s); err != nil {
+		return err
+	}
+	if err := enqueue(ctx, s); err != nil {
+		// the sentence never reached the queue, so there is nothing to replay
+		b.Ack(s.ID)
+		return err
+	}
+	return nil
+}
+
+// enqueue sends s to IngestChan without touching the backend.
+func enqueue(ctx context.Context, s Sentence) error {
+	queueMu.RLock()
+	ch := IngestChan
+	queueMu.RUnlock()
+	select {
+	case ch <- s:
+		submitted.Add(1)
+		return nil
+	case <-ctx.Done():
+		dropped.Add(1)
+		return ctx.Err()
+	}
+}
+
+// SubmitAll enqueues sentences in order with Submit, stopping at the first error.
+func SubmitAll(ctx context.Context, sentences []Sentence) error {
+	for _, s := range sentences {
+		if err := Submit(ctx, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TrySubmit records s in the backend and enqueues it without blocking. It returns
+// ErrQueueFull if there is no room, which is all it returns once the queue is full and
+// nothing consumes it.
+func TrySubmit(s Sentence) error {
+	queueMu.RLock()
+	ch, b := IngestChan, backend
+	queueMu.RUnlock()
+	if err := b.Append(s); err != nil {
+		return err
+	}
+	select {
+	case ch <- s:
+		submitted.Add(1)
+		return nil
+	default:
+		dropped.Add(1)
+		b.Ack(s.ID)
+		return ErrQueueFull
+	}
+}
+
+// Stats returns the current queue statistics.
+func Stats() QueueStats {
+	queueMu.RLock()
+	defer queueMu.RUnlock()
+	return QueueStats{
+		Queued:    len(IngestChan),
+		Capacity:  cap(IngestChan),
+		Submitted: submitted.Load(),
+		Dropped:   dropped.Load(),
+	}
+}
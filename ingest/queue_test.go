@@ -0,0 +1,36 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQueueBackpressure(t *testing.T) {
+	SetQueueSize(2)
+	defer SetQueueSize(DefaultQueueSize)
+	before := Stats()
+
+	for i := 0; i < 2; i++ {
+		if err := TrySubmit(Sentence{ID: "s"}); err != nil {
+			t.Fatalf("TrySubmit %d: %v", i, err)
+		}
+	}
+	if err := TrySubmit(Sentence{ID: "full"}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("TrySubmit on a full queue = %v; want ErrQueueFull", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := Submit(ctx, Sentence{ID: "blocked"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Submit on a full queue = %v; want deadline exceeded", err)
+	}
+
+	st := Stats()
+	if st.Queued != 2 || st.Capacity != 2 || st.Submitted-before.Submitted != 2 || st.Dropped-before.Dropped != 2 {
+		t.Errorf("stats = %+v (before %+v)", st, before)
+	}
+	if s := <-Queue(); s.ID != "s" {
+		t.Errorf("received %q", s.ID)
+	}
+}
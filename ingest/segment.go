@@ -0,0 +1,120 @@
+package ingest
+
+import "unicode"
+
+// Span is a rune range [Start, End) of a text.
+type Span struct {
+	Start int
+	End   int
+}
+
+// terminators end a sentence. Runs of them ("！？", "!!") stay with one sentence.
+var terminators = map[rune]bool{'。': true, '．': true, '！': true, '？': true, '!': true, '?': true}
+
+// brackets maps opening quotes and brackets to their closers. Text inside them is
+// never split, so 「行くよ。」と言った。 stays one sentence.
+var brackets = map[rune]rune{
+	'「': '」', '『': '』', '（': '）', '(': ')', '【': '】', '〈': '〉', '《': '》', '〔': '〕', '“': '”',
+}
+
+var closers = map[rune]bool{'」': true, '』': true, '）': true, ')': true, '】': true, '〉': true, '》': true, '〕': true, '”': true}
+
+// maxEmoticon is the longest parenthesized group, such as (笑) or (^_^), that is kept
+// with the sentence before it.
+const maxEmoticon = 8
+
+// SplitSentences splits text into sentence spans. It splits after 。！？ (and their
+// ASCII forms) and at newlines, but not inside 「」『』（） and similar brackets.
+// Ellipses (…, ‥, ...) do not end a sentence on their own, closing quotes after a
+// terminator stay with the sentence, and so does a short emoticon such as (笑) or (^^)
+// right after it. Spans are trimmed of surrounding whitespace; empty ones are dropped.
+func SplitSentences(text string) []Span {
+	runes := []rune(text)
+	var spans []Span
+	start, depth := 0, 0
+	var stack []rune
+
+	emit := func(end int) {
+		s, e := start, end
+		for s < e && unicode.IsSpace(runes[s]) {
+			s++
+		}
+		for e > s && unicode.IsSpace(runes[e-1]) {
+			e--
+		}
+		if s < e {
+			spans = append(spans, Span{Start: s, End: e})
+		}
+		start = end
+	}
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\n':
+			// a newline always ends a sentence, which also recovers from unbalanced quotes
+			stack, depth = stack[:0], 0
+			emit(i + 1)
+		case brackets[r] != 0:
+			stack = append(stack, brackets[r])
+			depth++
+		case closers[r]:
+			if depth > 0 && stack[depth-1] == r {
+				stack = stack[:depth-1]
+				depth--
+			}
+		case depth == 0 && isTerminator(runes, i):
+			j := i + 1
+			for j < len(runes) && isTerminator(runes, j) {
+				j++
+			}
+			for j < len(runes) && closers[runes[j]] {
+				j++
+			}
+			j += emoticonAt(runes, j)
+			emit(j)
+			i = j - 1
+		}
+	}
+	emit(len(runes))
+	return spans
+}
+
+// isTerminator reports whether runes[i] ends a sentence. An ASCII period only counts
+// when it is not part of a number (3.5) or an ellipsis (...).
+func isTerminator(runes []rune, i int) bool {
+	r := runes[i]
+	if r != '.' {
+		return terminators[r]
+	}
+	prevDot := i > 0 && runes[i-1] == '.'
+	nextDot := i+1 < len(runes) && runes[i+1] == '.'
+	if prevDot || nextDot {
+		return false
+	}
+	if i > 0 && unicode.IsDigit(runes[i-1]) && i+1 < len(runes) && unicode.IsDigit(runes[i+1]) {
+		return false
+	}
+	return true
+}
+
+// emoticonAt returns the length of a short parenthesized group starting at runes[i], or
+// 0 if there is none.
+func emoticonAt(runes []rune, i int) int {
+	if i >= len(runes) {
+		return 0
+	}
+	closer, ok := brackets[runes[i]]
+	if !ok || (runes[i] != '(' && runes[i] != '（') {
+		return 0
+	}
+	for j := i + 1; j < len(runes) && j-i <= maxEmoticon; j++ {
+		if runes[j] == closer {
+			return j - i + 1
+		}
+		if runes[j] == '\n' || runes[j] == '。' {
+			return 0
+		}
+	}
+	return 0
+}
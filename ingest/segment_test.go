@@ -0,0 +1,30 @@
+package ingest
+
+import "testing"
+
+func TestSplitSentences(t *testing.T) {
+	cases := map[string][]string{
+		"雨が降った。傘を持って行く！":           {"雨が降った。", "傘を持って行く！"},
+		"「行くよ。」と彼は言った。そうか？！":       {"「行くよ。」と彼は言った。", "そうか？！"},
+		"えっと…そうですね。楽しかった！(笑)また行こう": {"えっと…そうですね。", "楽しかった！(笑)", "また行こう"},
+		"1行目\n2行目":       {"1行目", "2行目"},
+		"値は3.5です...たぶん。": {"値は3.5です...たぶん。"},
+	}
+	for in, want := range cases {
+		runes := []rune(in)
+		spans := SplitSentences(in)
+		var got []string
+		for _, sp := range spans {
+			got = append(got, string(runes[sp.Start:sp.End]))
+		}
+		if len(got) != len(want) {
+			t.Errorf("SplitSentences(%q) = %q; want %q", in, got, want)
+			continue
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("SplitSentences(%q)[%d] = %q; want %q", in, i, got[i], want[i])
+			}
+		}
+	}
+}
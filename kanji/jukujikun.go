@@ -0,0 +1,113 @@
+package kanji
+
+// jukujikunList is a curated list of common jukujikun and ateji whose readings
+// belong to the word as a whole and cannot be split across individual kanji.
+// Readings are hiragana; okurigana are not included.
+var jukujikunList = map[string][]string{
+	"今日":  {"きょう"},
+	"明日":  {"あした", "あす"},
+	"昨日":  {"きのう"},
+	"今朝":  {"けさ"},
+	"今年":  {"ことし"},
+	"一昨日": {"おととい", "おとつい"},
+	"一昨年": {"おととし"},
+	"明後日": {"あさって"},
+	"一日":  {"ついたち"},
+	"二日":  {"ふつか"},
+	"二十日": {"はつか"},
+	"二十歳": {"はたち"},
+	"一人":  {"ひとり"},
+	"二人":  {"ふたり"},
+	"大人":  {"おとな"},
+	"五月雨": {"さみだれ"},
+	"梅雨":  {"つゆ"},
+	"時雨":  {"しぐれ"},
+	"吹雪":  {"ふぶき"},
+	"雪崩":  {"なだれ"},
+	"紅葉":  {"もみじ"},
+	"七夕":  {"たなばた"},
+	"日和":  {"ひより"},
+	"景色":  {"けしき"},
+	"果物":  {"くだもの"},
+	"時計":  {"とけい"},
+	"眼鏡":  {"めがね"},
+	"土産":  {"みやげ"},
+	"部屋":  {"へや"},
+	"上手":  {"じょうず"},
+	"下手":  {"へた"},
+	"田舎":  {"いなか"},
+	"博士":  {"はかせ"},
+	"真面目": {"まじめ"},
+	"風邪":  {"かぜ"},
+	"浴衣":  {"ゆかた"},
+	"足袋":  {"たび"},
+	"河原":  {"かわら"},
+	"川原":  {"かわら"},
+	"仲人":  {"なこうど"},
+	"八百屋": {"やおや"},
+	"迷子":  {"まいご"},
+	"息子":  {"むすこ"},
+	"若人":  {"わこうど"},
+	"玄人":  {"くろうと"},
+	"素人":  {"しろうと"},
+	"乙女":  {"おとめ"},
+	"母":   {"かあ"},
+	"父":   {"とう"},
+	"兄":   {"にい"},
+	"姉":   {"ねえ"},
+	"海女":  {"あま"},
+	"海苔":  {"のり"},
+	"心地":  {"ここち"},
+	"竹刀":  {"しない"},
+	"太刀":  {"たち"},
+	"相撲":  {"すもう"},
+	"芝生":  {"しばふ"},
+	"清水":  {"しみず"},
+	"三味線": {"しゃみせん"},
+	"寄席":  {"よせ"},
+	"山車":  {"だし"},
+	"数珠":  {"じゅず"},
+	"為替":  {"かわせ"},
+	"読経":  {"どきょう"},
+	"百合":  {"ゆり"},
+	"木綿":  {"もめん"},
+	"砂利":  {"じゃり"},
+	"白髪":  {"しらが"},
+	"老舗":  {"しにせ"},
+	"居士":  {"こじ"},
+	"稚児":  {"ちご"},
+	"築山":  {"つきやま"},
+	"波止場": {"はとば"},
+	"野良":  {"のら"},
+	"神楽":  {"かぐら"},
+	"雑魚":  {"ざこ"},
+	"早乙女": {"さおとめ"},
+	"五月":  {"さつき"},
+	"師走":  {"しわす"},
+	"行方":  {"ゆくえ"},
+	"最寄":  {"もより"},
+	"尻尾":  {"しっぽ"},
+	"名残":  {"なごり"},
+	"小豆":  {"あずき"},
+	"蚊帳":  {"かや"},
+	"硫黄":  {"いおう"},
+	"意気地": {"いくじ"},
+	"昨夜":  {"ゆうべ"},
+	"今宵":  {"こよい"},
+}
+
+// JukujikunReadings returns the curated whole-word readings for word, if any.
+func JukujikunReadings(word string) []string {
+	return jukujikunList[word]
+}
+
+// IsJukujikun reports whether reading (hiragana) is a curated jukujikun or ateji
+// reading of word.
+func IsJukujikun(word, reading string) bool {
+	for _, r := range jukujikunList[word] {
+		if r == reading {
+			return true
+		}
+	}
+	return false
+}
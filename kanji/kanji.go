@@ -2,9 +2,11 @@ package kanji
 
 import (
 	"encoding/xml"
+	"fmt"
 	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"unicode/utf8"
@@ -31,6 +33,23 @@ func RendakuForm(s string) string {
 	return s
 }
 
+// handakuMap maps the は row to its semi-voiced forms.
+var handakuMap = map[rune]rune{'は': 'ぱ', 'ひ': 'ぴ', 'ふ': 'ぷ', 'へ': 'ぺ', 'ほ': 'ぽ'}
+
+// HandakuForm returns the semi-voiced form of a hiragana string starting in the は row,
+// as after っ or ん in compounds (発表 はっぴょう, 散歩 さんぽ), or s unchanged.
+func HandakuForm(s string) string {
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return s
+	}
+	if v, ok := handakuMap[runes[0]]; ok {
+		runes[0] = v
+		return string(runes)
+	}
+	return s
+}
+
 // NormalizeReading removes non-kana characters (dots, hyphens) and
 // converts katakana to hiragana so kanjidic readings like "い.り" match "いり".
 func NormalizeReading(s string) string {
@@ -53,11 +72,19 @@ func NormalizeReading(s string) string {
 
 var (
 	kanjiReadingMap     map[rune][]string
+	kanjiGradeMap       map[rune]int
+	kanjiJLPTMap        map[rune]int
 	kanjiReadingMapOnce sync.Once
+	// version identifies the file InitKanjidic2 loaded; see Version
+	version = "none"
 )
 
 type Kanjidic2Kanji struct {
-	Literal        string `xml:"literal"`
+	Literal string `xml:"literal"`
+	Misc    struct {
+		Grade int `xml:"grade"`
+		JLPT  int `xml:"jlpt"`
+	} `xml:"misc"`
 	ReadingMeaning struct {
 		RMGroup []struct {
 			Reading []struct {
@@ -73,6 +100,8 @@ func InitKanjidic2(path string) error {
 	var err error
 	kanjiReadingMapOnce.Do(func() {
 		kanjiReadingMap = make(map[rune][]string)
+		kanjiGradeMap = make(map[rune]int)
+		kanjiJLPTMap = make(map[rune]int)
 		var loadedKanji []string
 		f, fileErr := os.Open(path)
 		if fileErr != nil {
@@ -113,6 +142,12 @@ func InitKanjidic2(path string) error {
 					}
 					kanjiRune, _ := utf8.DecodeRuneInString(k.Literal)
 					kanjiReadingMap[kanjiRune] = readings
+					if k.Misc.Grade > 0 {
+						kanjiGradeMap[kanjiRune] = k.Misc.Grade
+					}
+					if k.Misc.JLPT > 0 {
+						kanjiJLPTMap[kanjiRune] = k.Misc.JLPT
+					}
 					if len(loadedKanji) < 10 {
 						loadedKanji = append(loadedKanji, k.Literal+": "+strings.Join(readings, ", "))
 					}
@@ -122,6 +157,9 @@ func InitKanjidic2(path string) error {
 				}
 			}
 		}
+		if fi, statErr := f.Stat(); statErr == nil {
+			version = fmt.Sprintf("%s:%d:%d", filepath.Base(path), fi.Size(), fi.ModTime().Unix())
+		}
 		log.Printf("First 10 kanji loaded: %v", loadedKanji)
 		log.Printf("Kanjidic2 loaded: %d kanji entries", len(kanjiReadingMap))
 	})
@@ -154,6 +192,12 @@ func GetKanjiReadings(r rune) []string {
 	return readings
 }
 
+// Version identifies the loaded Kanjidic2 file by name, size and modification time, so
+// caches can tell when it changes. It is "none" until a file has been loaded.
+func Version() string {
+	return version
+}
+
 // Count returns the number of kanji entries loaded
 func Count() int {
 	if kanjiReadingMap == nil {
@@ -161,3 +205,39 @@ func Count() int {
 	}
 	return len(kanjiReadingMap)
 }
+
+// GetKanjiGrade returns the Kanjidic2 school grade for a kanji (1-6 elementary,
+// 8 secondary Joyo, 9-10 Jinmeiyo), or 0 if the kanji has no grade.
+func GetKanjiGrade(r rune) int {
+	if kanjiGradeMap == nil {
+		return 0
+	}
+	return kanjiGradeMap[r]
+}
+
+// GetKanjiJLPT returns the Kanjidic2 (pre-2010, 4 = easiest) JLPT level for a kanji,
+// or 0 if the kanji is not part of any level.
+func GetKanjiJLPT(r rune) int {
+	if kanjiJLPTMap == nil {
+		return 0
+	}
+	return kanjiJLPTMap[r]
+}
+
+// Info is what Kanjidic2 records about one kanji.
+type Info struct {
+	Kanji    string   `json:"kanji"`
+	Readings []string `json:"readings,omitempty"`
+	Grade    int      `json:"grade,omitempty"`
+	JLPT     int      `json:"jlpt,omitempty"`
+}
+
+// GetKanjiInfo returns the readings, grade and JLPT level of a kanji. Fields are empty
+// if Kanjidic2 is not loaded or does not list it.
+func GetKanjiInfo(r rune) Info {
+	info := Info{Kanji: string(r), Grade: GetKanjiGrade(r), JLPT: GetKanjiJLPT(r)}
+	if kanjiReadingMap != nil {
+		info.Readings = kanjiReadingMap[r]
+	}
+	return info
+}
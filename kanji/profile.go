@@ -0,0 +1,76 @@
+package kanji
+
+// ReaderProfile describes which kanji a reader already knows so furigana can be
+// limited to words that contain something unfamiliar. A kanji counts as known if
+// it satisfies any of the configured criteria.
+type ReaderProfile struct {
+	// Grade is the highest Kanjidic2 school grade the reader knows (1-6, or 8 for
+	// all Joyo kanji). 0 disables the grade check.
+	Grade int `json:"grade,omitempty"`
+	// JLPT is the hardest Kanjidic2 JLPT level the reader knows (4 = easiest,
+	// 1 = hardest). 0 disables the JLPT check.
+	JLPT int `json:"jlpt,omitempty"`
+	// Known is an explicit set of kanji the reader knows.
+	Known map[rune]bool `json:"known,omitempty"`
+}
+
+// NewGradeProfile returns a profile for a reader who knows every kanji up to grade.
+func NewGradeProfile(grade int) *ReaderProfile {
+	return &ReaderProfile{Grade: grade}
+}
+
+// NewJLPTProfile returns a profile for a reader who knows every kanji down to level.
+func NewJLPTProfile(level int) *ReaderProfile {
+	return &ReaderProfile{JLPT: level}
+}
+
+// NewKnownKanjiProfile returns a profile built from an explicit list of kanji.
+// Any non-kanji characters in known are ignored.
+func NewKnownKanjiProfile(known string) *ReaderProfile {
+	p := &ReaderProfile{Known: make(map[rune]bool)}
+	for _, r := range known {
+		if IsKanji(r) {
+			p.Known[r] = true
+		}
+	}
+	return p
+}
+
+// IsKanji reports whether r is in the CJK Unified Ideographs block.
+func IsKanji(r rune) bool {
+	return r >= 0x4E00 && r <= 0x9FFF
+}
+
+// Knows reports whether the reader knows kanji r. A nil profile knows nothing.
+func (p *ReaderProfile) Knows(r rune) bool {
+	if p == nil {
+		return false
+	}
+	if p.Known[r] {
+		return true
+	}
+	if p.Grade > 0 {
+		// Kanjidic2 grades 9 and 10 are Jinmeiyo (names only), never covered by a grade profile
+		if g := GetKanjiGrade(r); g > 0 && g <= p.Grade && g <= 8 {
+			return true
+		}
+	}
+	if p.JLPT > 0 {
+		// old JLPT levels count down: a level 2 reader also knows levels 3 and 4
+		if l := GetKanjiJLPT(r); l > 0 && l >= p.JLPT {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsFurigana reports whether word contains at least one kanji outside the profile.
+// A nil profile requires furigana on every word containing kanji.
+func (p *ReaderProfile) NeedsFurigana(word string) bool {
+	for _, r := range word {
+		if IsKanji(r) && !p.Knows(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package kanji
+
+import "testing"
+
+func TestReaderProfile(t *testing.T) {
+	if err := InitKanjidic2("testdata/kanjidic2_profile.xml"); err != nil {
+		t.Fatal(err)
+	}
+	// grade and old JLPT level of the test kanji:
+	// 日 1/4, 紙 2/3, 結 4/3, 緒 8/2, 鬱 8/1, 彦 9 (Jinmeiyo, no JLPT level)
+	cases := []struct {
+		name    string
+		profile *ReaderProfile
+		knows   string // kanji the profile knows among 日紙結緒鬱彦
+	}{
+		{"nil", nil, ""},
+		{"grade 1", NewGradeProfile(1), "日"},
+		{"grade 2", NewGradeProfile(2), "日紙"},
+		{"grade 6", NewGradeProfile(6), "日紙結"},
+		{"grade 8", NewGradeProfile(8), "日紙結緒鬱"},
+		{"grade 10 stops at Joyo", NewGradeProfile(10), "日紙結緒鬱"},
+		{"jlpt 4", NewJLPTProfile(4), "日"},
+		{"jlpt 3", NewJLPTProfile(3), "日紙結"},
+		{"jlpt 2", NewJLPTProfile(2), "日紙結緒"},
+		{"jlpt 1", NewJLPTProfile(1), "日紙結緒鬱"},
+		{"known", NewKnownKanjiProfile("彦と鬱"), "鬱彦"},
+		{"known or grade", &ReaderProfile{Grade: 1, Known: NewKnownKanjiProfile("緒").Known}, "日緒"},
+	}
+	for _, c := range cases {
+		for _, r := range "日紙結緒鬱彦" {
+			want := false
+			for _, k := range c.knows {
+				want = want || k == r
+			}
+			if got := c.profile.Knows(r); got != want {
+				t.Errorf("%s: Knows(%c) = %v, want %v", c.name, r, got, want)
+			}
+		}
+	}
+}
+
+func TestNeedsFurigana(t *testing.T) {
+	if err := InitKanjidic2("testdata/kanjidic2_profile.xml"); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		profile *ReaderProfile
+		word    string
+		want    bool
+	}{
+		{nil, "日", true},
+		{nil, "ひらがな", false},
+		{NewGradeProfile(2), "日紙", false},
+		{NewGradeProfile(2), "紙結", true}, // one unknown kanji is enough
+		{NewGradeProfile(4), "結ぶ", false},
+		{NewJLPTProfile(3), "結緒", true},
+		{NewJLPTProfile(2), "結緒", false},
+		{NewKnownKanjiProfile("彦"), "彦", false},
+		{NewKnownKanjiProfile("彦"), "日彦", true},
+		{NewKnownKanjiProfile("abc"), "カナ", false},
+	}
+	for _, c := range cases {
+		if got := c.profile.NeedsFurigana(c.word); got != c.want {
+			t.Errorf("%+v.NeedsFurigana(%s) = %v, want %v", c.profile, c.word, got, c.want)
+		}
+	}
+}
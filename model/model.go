@@ -19,6 +19,8 @@ type Token struct {
 	DictionaryEntry  DictionaryEntry `json:"dictionary_entry,omitempty"`
 	FuriganaText     string          `json:"furigana_text,omitempty"`
 	FuriganaLemma    string          `json:"furigana_lemma,omitempty"`
+	Jukujikun        bool            `json:"jukujikun,omitempty"`     // reading applies to the whole word, not per kanji
+	NumericValue     *int64          `json:"numeric_value,omitempty"` // normalized value of numeral tokens
 }
 
 type DictionaryEntry struct {
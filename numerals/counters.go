@@ -0,0 +1,174 @@
+package numerals
+
+// soundClass groups counters by how their first mora changes after a number.
+type soundClass int
+
+const (
+	classNone soundClass = iota
+	// classH counters start with h: いっぽん, ろっぽん, はっぽん, じゅっぽん, ひゃっぽん
+	classH
+	// classK counters start with k: いっこ, ろっこ, はっこ, じゅっこ, ひゃっこ
+	classK
+	// classS counters start with s/sh: いっさつ, はっさつ, じゅっさつ
+	classS
+	// classT counters start with t/ch/ts: いっとう, はっとう, じゅっとう
+	classT
+)
+
+type counter struct {
+	reading string
+	class   soundClass
+	// voiced3 voices the counter after 3 (and 1000, 万 for classH): さんぼん, さんがい
+	voiced3 bool
+	// handakuten4 gives the p-form after 4: よんぷん
+	handakuten4 bool
+	// digits overrides the reading of the final digit: よにん, しちじ, くがつ
+	digits map[int]string
+	// special holds whole readings for specific values: ひとり, ついたち, はたち
+	special map[int64]string
+}
+
+var yoShichiKu = map[int]string{4: "よ", 7: "しち", 9: "く"}
+
+var dayReadings = map[int64]string{
+	1: "ついたち", 2: "ふつか", 3: "みっか", 4: "よっか", 5: "いつか",
+	6: "むいか", 7: "なのか", 8: "ようか", 9: "ここのか", 10: "とおか",
+	14: "じゅうよっか", 20: "はつか", 24: "にじゅうよっか",
+}
+
+// dayCountReadings are the irregular readings of a number of days (みっかかん); one day
+// is いちにちかん, not ついたち.
+func dayCountReadings() map[int64]string {
+	m := make(map[int64]string, len(dayReadings))
+	for n, r := range dayReadings {
+		if n != 1 {
+			m[n] = r + "かん"
+		}
+	}
+	return m
+}
+
+// counters maps counter surfaces to their reading rules.
+var counters = map[string]counter{
+	// people, days, things
+	"人":  {reading: "にん", digits: map[int]string{4: "よ", 7: "しち"}, special: map[int64]string{1: "ひとり", 2: "ふたり"}},
+	"名":  {reading: "めい"},
+	"日":  {reading: "にち", special: dayReadings},
+	"日間": {reading: "にちかん", special: dayCountReadings()},
+	"つ": {reading: "つ", special: map[int64]string{
+		1: "ひとつ", 2: "ふたつ", 3: "みっつ", 4: "よっつ", 5: "いつつ",
+		6: "むっつ", 7: "ななつ", 8: "やっつ", 9: "ここのつ", 10: "とお"}},
+
+	// time
+	"時":  {reading: "じ", digits: yoShichiKu},
+	"時間": {reading: "じかん", digits: yoShichiKu},
+	"分":  {reading: "ふん", class: classH, handakuten4: true},
+	"分間": {reading: "ふんかん", class: classH, handakuten4: true},
+	"秒":  {reading: "びょう"},
+	"月":  {reading: "がつ", digits: map[int]string{4: "し", 7: "しち", 9: "く"}},
+	"か月": {reading: "かげつ", class: classK},
+	"ヶ月": {reading: "かげつ", class: classK},
+	"ケ月": {reading: "かげつ", class: classK},
+	"カ月": {reading: "かげつ", class: classK},
+	"年":  {reading: "ねん", digits: map[int]string{4: "よ"}},
+	"年間": {reading: "ねんかん", digits: map[int]string{4: "よ"}},
+	"週":  {reading: "しゅう", class: classS},
+	"週間": {reading: "しゅうかん", class: classS},
+	"歳":  {reading: "さい", class: classS, special: map[int64]string{20: "はたち"}},
+	"才":  {reading: "さい", class: classS, special: map[int64]string{20: "はたち"}},
+
+	// h-row counters
+	"本": {reading: "ほん", class: classH, voiced3: true},
+	"匹": {reading: "ひき", class: classH, voiced3: true},
+	"杯": {reading: "はい", class: classH, voiced3: true},
+	"泊": {reading: "はく", class: classH},
+	"発": {reading: "はつ", class: classH},
+	"歩": {reading: "ほ", class: classH},
+	"票": {reading: "ひょう", class: classH},
+
+	// k-row counters
+	"個":  {reading: "こ", class: classK},
+	"回":  {reading: "かい", class: classK},
+	"階":  {reading: "かい", class: classK, voiced3: true},
+	"件":  {reading: "けん", class: classK},
+	"軒":  {reading: "けん", class: classK, voiced3: true},
+	"巻":  {reading: "かん", class: classK},
+	"曲":  {reading: "きょく", class: classK},
+	"課":  {reading: "か", class: classK},
+	"か所": {reading: "かしょ", class: classK},
+	"カ所": {reading: "かしょ", class: classK},
+	"ヶ所": {reading: "かしょ", class: classK},
+	"か国": {reading: "かこく", class: classK},
+	"カ国": {reading: "かこく", class: classK},
+	"ヶ国": {reading: "かこく", class: classK},
+
+	// s-row counters
+	"冊":  {reading: "さつ", class: classS},
+	"足":  {reading: "そく", class: classS, voiced3: true},
+	"世帯": {reading: "せたい", class: classS},
+	"隻":  {reading: "せき", class: classS},
+
+	// t-row counters
+	"頭": {reading: "とう", class: classT},
+	"通": {reading: "つう", class: classT},
+	"点": {reading: "てん", class: classT},
+	"着": {reading: "ちゃく", class: classT},
+	"丁": {reading: "ちょう", class: classT},
+
+	// no sound change
+	"円":     {reading: "えん", digits: map[int]string{4: "よ"}},
+	"度":     {reading: "ど"},
+	"番":     {reading: "ばん"},
+	"枚":     {reading: "まい"},
+	"台":     {reading: "だい"},
+	"段":     {reading: "だん"},
+	"段階":    {reading: "だんかい"},
+	"割":     {reading: "わり"},
+	"倍":     {reading: "ばい"},
+	"号":     {reading: "ごう"},
+	"位":     {reading: "い"},
+	"問":     {reading: "もん"},
+	"部":     {reading: "ぶ"},
+	"キロ":    {reading: "きろ"},
+	"メートル":  {reading: "めーとる"},
+	"センチ":   {reading: "せんち", class: classS},
+	"パーセント": {reading: "ぱーせんと", class: classH},
+	"%":     {reading: "ぱーせんと", class: classH},
+	"％":     {reading: "ぱーせんと", class: classH},
+}
+
+// IsCounter reports whether s is a counter with known reading rules.
+func IsCounter(s string) bool {
+	_, ok := counters[s]
+	return ok
+}
+
+// counterCategories groups counters by what they measure.
+var counterCategories = map[string]string{
+	"人": "people", "名": "people",
+	"世帯": "households",
+	"時":  "time", "分": "time", "秒": "time",
+	"時間": "duration", "分間": "duration", "日間": "duration", "週間": "duration", "年間": "duration",
+	"か月": "duration", "ヶ月": "duration", "ケ月": "duration", "カ月": "duration", "週": "duration",
+	"年": "date", "月": "date", "日": "date",
+	"歳": "age", "才": "age",
+	"円": "currency",
+	"%": "percent", "％": "percent", "パーセント": "percent", "割": "ratio", "倍": "ratio",
+	"キロ": "length", "メートル": "length", "センチ": "length",
+	"段階": "level", "段": "level", "階": "level", "位": "rank", "番": "rank", "号": "rank",
+	"か所": "places", "カ所": "places", "ヶ所": "places",
+	"か国": "countries", "カ国": "countries", "ヶ国": "countries",
+	"回": "times", "度": "times",
+}
+
+// CounterCategory returns what a counter measures ("people", "time", "currency", ...).
+// Known counters without a more specific category are "count"; unknown ones are "".
+func CounterCategory(s string) string {
+	if c, ok := counterCategories[s]; ok {
+		return c
+	}
+	if IsCounter(s) {
+		return "count"
+	}
+	return ""
+}
@@ -0,0 +1,138 @@
+package numerals
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Style selects how Format writes a number.
+type Style int
+
+const (
+	// Arabic writes ASCII digits: 2345
+	Arabic Style = iota
+	// FullWidth writes full-width digits: ２３４５
+	FullWidth
+	// Kanji writes kanji numerals with units: 二千三百四十五
+	Kanji
+	// KanjiPositional writes one kanji per digit: 二三四五
+	KanjiPositional
+	// Daiji writes the 大字 used on legal documents, always writing 壱: 弐千参百四拾五
+	Daiji
+	// Mixed writes Arabic digits grouped by 万/億/兆, as in news text: 3万5000
+	Mixed
+)
+
+var kanjiDigitChars = []string{"〇", "一", "二", "三", "四", "五", "六", "七", "八", "九"}
+
+var daijiDigitChars = []string{"零", "壱", "弐", "参", "四", "五", "六", "七", "八", "九"}
+
+// Format writes n in the given style. Units go up to 京 (10^16), which covers every
+// int64.
+func Format(n int64, style Style) string {
+	if n < 0 {
+		// negate as uint64: -math.MinInt64 does not fit in an int64
+		return "-" + formatMagnitude(uint64(-n), style)
+	}
+	return formatMagnitude(uint64(n), style)
+}
+
+func formatMagnitude(n uint64, style Style) string {
+	switch style {
+	case FullWidth:
+		return toFullWidth(strconv.FormatUint(n, 10))
+	case Kanji:
+		return formatUnits(n, kanjiDigitChars, []string{"十", "百", "千"}, []string{"万", "億", "兆", "京"}, false)
+	case KanjiPositional:
+		var sb strings.Builder
+		for _, r := range strconv.FormatUint(n, 10) {
+			sb.WriteString(kanjiDigitChars[r-'0'])
+		}
+		return sb.String()
+	case Daiji:
+		return formatUnits(n, daijiDigitChars, []string{"拾", "百", "千"}, []string{"萬", "億", "兆", "京"}, true)
+	case Mixed:
+		return formatMixed(n)
+	}
+	return strconv.FormatUint(n, 10)
+}
+
+// formatUnits writes n with unit characters. explicitOne writes the digit 1 before
+// units (壱拾), as daiji require.
+func formatUnits(n uint64, digits, small, large []string, explicitOne bool) string {
+	if n == 0 {
+		return digits[0]
+	}
+	var sb strings.Builder
+	groups := splitGroups(n)
+	for i := len(groups) - 1; i >= 0; i-- {
+		g := groups[i]
+		if g == 0 {
+			continue
+		}
+		for p := 3; p >= 0; p-- {
+			d := g / pow10(p) % 10
+			if d == 0 {
+				continue
+			}
+			if p == 0 || d != 1 || explicitOne {
+				sb.WriteString(digits[d])
+			}
+			if p > 0 {
+				sb.WriteString(small[p-1])
+			}
+		}
+		if i > 0 {
+			sb.WriteString(large[i-1])
+		}
+	}
+	return sb.String()
+}
+
+// formatMixed writes Arabic digits for each 万 group: 35000 → 3万5000.
+func formatMixed(n uint64) string {
+	if n == 0 {
+		return "0"
+	}
+	large := []string{"万", "億", "兆", "京"}
+	var sb strings.Builder
+	groups := splitGroups(n)
+	for i := len(groups) - 1; i >= 0; i-- {
+		if groups[i] == 0 {
+			continue
+		}
+		sb.WriteString(strconv.FormatUint(groups[i], 10))
+		if i > 0 {
+			sb.WriteString(large[i-1])
+		}
+	}
+	return sb.String()
+}
+
+// splitGroups splits n into four-digit groups, least significant first.
+func splitGroups(n uint64) []uint64 {
+	var groups []uint64
+	for n > 0 {
+		groups = append(groups, n%10000)
+		n /= 10000
+	}
+	return groups
+}
+
+func pow10(p int) uint64 {
+	v := uint64(1)
+	for i := 0; i < p; i++ {
+		v *= 10
+	}
+	return v
+}
+
+func toFullWidth(s string) string {
+	r := []rune(s)
+	for i, c := range r {
+		if c >= '0' && c <= '9' {
+			r[i] = c - '0' + '０'
+		}
+	}
+	return string(r)
+}
@@ -0,0 +1,141 @@
+// Package numerals parses and formats Japanese numerals and generates their readings,
+// including the sound changes that happen when a number is followed by a counter.
+package numerals
+
+import (
+	"math"
+	"strings"
+)
+
+// kanjiDigits maps kanji and positional numerals, including the 大字 (daiji) used on
+// legal and financial documents, to their digit value.
+var kanjiDigits = map[rune]int64{
+	'〇': 0, '零': 0,
+	'一': 1, '二': 2, '三': 3, '四': 4, '五': 5,
+	'六': 6, '七': 7, '八': 8, '九': 9,
+	'壱': 1, '壹': 1, '弌': 1, '弐': 2, '貳': 2, '弍': 2, '参': 3, '參': 3, '弎': 3,
+	'肆': 4, '伍': 5, '陸': 6, '漆': 7, '柒': 7, '捌': 8, '玖': 9,
+}
+
+// smallUnits multiply the digit before them within a four-digit group.
+var smallUnits = map[rune]int64{
+	'十': 10, '百': 100, '千': 1000,
+	'拾': 10, '佰': 100, '陌': 100, '仟': 1000, '阡': 1000,
+}
+
+// largeUnits close a four-digit group.
+var largeUnits = map[rune]int64{'万': 1e4, '萬': 1e4, '億': 1e8, '兆': 1e12, '京': 1e16}
+
+// digitValue returns the value of an ASCII, full-width or kanji digit.
+func digitValue(r rune) (int64, bool) {
+	switch {
+	case r >= '0' && r <= '9':
+		return int64(r - '0'), true
+	case r >= '０' && r <= '９':
+		return int64(r - '０'), true
+	}
+	v, ok := kanjiDigits[r]
+	return v, ok
+}
+
+// IsNumeralRune reports whether r can be part of a numeral: a digit, a kanji digit or a
+// kanji unit.
+func IsNumeralRune(r rune) bool {
+	if _, ok := digitValue(r); ok {
+		return true
+	}
+	if _, ok := smallUnits[r]; ok {
+		return true
+	}
+	_, ok := largeUnits[r]
+	return ok
+}
+
+// IsNumeral reports whether every rune of s is a numeral rune (commas between digits
+// allowed).
+func IsNumeral(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r != ',' && r != '，' && !IsNumeralRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Parse converts a numeral to its value. It accepts Arabic and full-width digits
+// ("283", "２０２３"), kanji numerals with units ("三百六十五") or positional
+// ("二〇二三"), daiji ("壱萬弐千"), and mixed forms ("1万2千", "3万5000").
+// Thousands separators are ignored. The bool is false for values that do not fit in
+// an int64.
+func Parse(s string) (int64, bool) {
+	s = strings.NewReplacer(",", "", "，", "").Replace(s)
+	if s == "" {
+		return 0, false
+	}
+	var total, group, digits int64
+	haveDigits := false
+	ok := true
+	for _, r := range s {
+		if d, isDigit := digitValue(r); isDigit {
+			digits = add(mul(digits, 10, &ok), d, &ok)
+			haveDigits = true
+			continue
+		}
+		if u, isUnit := smallUnits[r]; isUnit {
+			if !haveDigits {
+				digits = 1
+			}
+			group = add(group, mul(digits, u, &ok), &ok)
+			digits, haveDigits = 0, false
+			continue
+		}
+		if u, isUnit := largeUnits[r]; isUnit {
+			group = add(group, digits, &ok)
+			if group == 0 {
+				group = 1
+			}
+			total = add(total, mul(group, u, &ok), &ok)
+			group, digits, haveDigits = 0, 0, false
+			continue
+		}
+		return 0, false
+	}
+	n := add(add(total, group, &ok), digits, &ok)
+	if !ok {
+		return 0, false
+	}
+	return n, true
+}
+
+// mul and add compute a*b and a+b for non-negative operands, clearing *ok on overflow.
+func mul(a, b int64, ok *bool) int64 {
+	if b != 0 && a > math.MaxInt64/b {
+		*ok = false
+		return 0
+	}
+	return a * b
+}
+
+func add(a, b int64, ok *bool) int64 {
+	if a > math.MaxInt64-b {
+		*ok = false
+		return 0
+	}
+	return a + b
+}
+
+// SplitNumeralPrefix splits s into its leading numeral and the remainder, e.g.
+// "283世帯" → ("283", "世帯").
+func SplitNumeralPrefix(s string) (string, string) {
+	end := 0
+	for i, r := range s {
+		if !IsNumeralRune(r) && r != ',' && r != '，' {
+			break
+		}
+		end = i + len(string(r))
+	}
+	return s[:end], s[end:]
+}
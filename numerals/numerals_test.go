@@ -0,0 +1,143 @@
+package numerals
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := map[string]int64{
+		"283":   283,
+		"２０２３":  2023,
+		"三百六十五": 365,
+		"二〇二三":  2023,
+		"1万2千":  12000,
+		"1000万": 10000000,
+		"十":     10,
+		"1,000": 1000,
+		"三億五千万": 350000000,
+		"壱萬弐千":  12000,
+		"参拾":    30,
+	}
+	for in, want := range cases {
+		if got, ok := Parse(in); !ok || got != want {
+			t.Errorf("Parse(%q) = %d, %v; want %d", in, got, ok, want)
+		}
+	}
+}
+
+func TestReadWithCounter(t *testing.T) {
+	cases := []struct {
+		n       int64
+		counter string
+		want    string
+	}{
+		{8, "時", "はちじ"},
+		{40, "分", "よんじゅっぷん"},
+		{283, "世帯", "にひゃくはちじゅうさんせたい"},
+		{649, "人", "ろっぴゃくよんじゅうきゅうにん"},
+		{1, "本", "いっぽん"},
+		{3, "本", "さんぼん"},
+		{6, "本", "ろっぽん"},
+		{8, "本", "はっぽん"},
+		{10, "本", "じゅっぽん"},
+		{600, "本", "ろっぴゃっぽん"},
+		{4, "分", "よんぷん"},
+		{1, "人", "ひとり"},
+		{2, "人", "ふたり"},
+		{4, "人", "よにん"},
+		{3, "階", "さんがい"},
+		{1, "個", "いっこ"},
+		{4, "月", "しがつ"},
+		{9, "時", "くじ"},
+		{20, "日", "はつか"},
+		{3, "日間", "みっかかん"},
+		{1, "日間", "いちにちかん"},
+	}
+	for _, c := range cases {
+		r, _ := ReadWithCounter(c.n, c.counter)
+		if got := r.String(); got != c.want {
+			t.Errorf("ReadWithCounter(%d, %q) = %s; want %s", c.n, c.counter, got, c.want)
+		}
+	}
+}
+
+func TestReadNumber(t *testing.T) {
+	cases := map[int64]string{
+		300:           "さんびゃく",
+		800:           "はっぴゃく",
+		3000:          "さんぜん",
+		10000:         "いちまん",
+		10000000:      "いっせんまん",
+		1000000000000: "いっちょう",
+		// past 兆 (regression: these used to index past the digit table)
+		10000000000000000:   "いっけい",
+		80000000000000000:   "はっけい",
+		9223372036854775807: "きゅうひゃくにじゅうにけいさんぜんさんびゃくななじゅうにちょうさんびゃくろくじゅうはちおくごせんよんひゃくななじゅうななまんごせんはっぴゃくなな",
+	}
+	for n, want := range cases {
+		if got := ReadNumber(n); got != want {
+			t.Errorf("ReadNumber(%d) = %s; want %s", n, got, want)
+		}
+	}
+	if got := ReadNumber(math.MinInt64); !strings.HasPrefix(got, "まいなすきゅうひゃくにじゅうにけい") {
+		t.Errorf("ReadNumber(MinInt64) = %s", got)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	cases := []struct {
+		n     int64
+		style Style
+		want  string
+	}{
+		{2023, Arabic, "2023"},
+		{2023, FullWidth, "２０２３"},
+		{2023, Kanji, "二千二十三"},
+		{2023, KanjiPositional, "二〇二三"},
+		{12000, Daiji, "壱萬弐千"},
+		{35000, Mixed, "3万5000"},
+		{0, Kanji, "〇"},
+		{1e16, Kanji, "一京"},
+		{1e16, Daiji, "壱京"},
+		{1e16, Mixed, "1京"},
+		{math.MaxInt64, Mixed, "922京3372兆368億5477万5807"},
+	}
+	for _, c := range cases {
+		got := Format(c.n, c.style)
+		if got != c.want {
+			t.Errorf("Format(%d, %v) = %q; want %q", c.n, c.style, got, c.want)
+		}
+		if v, ok := Parse(got); !ok || v != c.n {
+			t.Errorf("Parse(Format(%d, %v)) = %d, %v", c.n, c.style, v, ok)
+		}
+	}
+	// negating math.MinInt64 overflows; it used to recurse forever
+	if got := Format(math.MinInt64, Kanji); got != "-九百二十二京三千三百七十二兆三百六十八億五千四百七十七万五千八百八" {
+		t.Errorf("Format(MinInt64, Kanji) = %s", got)
+	}
+	if got := Format(math.MinInt64, Arabic); got != "-9223372036854775808" {
+		t.Errorf("Format(MinInt64, Arabic) = %s", got)
+	}
+}
+
+func TestParseOverflow(t *testing.T) {
+	cases := []struct {
+		in string
+		ok bool
+	}{
+		{"9223372036854775807", true},
+		{"922京3372兆368億5477万5807", true},
+		{"9223372036854775808", false},
+		{"99999999999999999999", false},
+		{"1000京", false},
+		{"九千九百九十九京", false},
+	}
+	for _, c := range cases {
+		v, ok := Parse(c.in)
+		if ok != c.ok || (ok && v != math.MaxInt64) {
+			t.Errorf("Parse(%q) = %d, %v; want ok=%v", c.in, v, ok, c.ok)
+		}
+	}
+}
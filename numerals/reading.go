@@ -0,0 +1,292 @@
+package numerals
+
+import "strings"
+
+// digitReadings are the default readings of the digits 0-9.
+var digitReadings = [10]string{"ぜろ", "いち", "に", "さん", "よん", "ご", "ろく", "なな", "はち", "きゅう"}
+
+// readGroup reads a value below 10000, applying the sound changes inside the number
+// (さんびゃく, ろっぴゃく, はっぴゃく, さんぜん, はっせん). leadingOne keeps いち before
+// せん, as in いっせんまん.
+func readGroup(g int64, leadingOne bool) string {
+	var sb strings.Builder
+	th, h, t, o := g/1000, g/100%10, g/10%10, g%10
+	switch th {
+	case 0:
+	case 1:
+		if leadingOne {
+			sb.WriteString("いっ")
+		}
+		sb.WriteString("せん")
+	case 3:
+		sb.WriteString("さんぜん")
+	case 8:
+		sb.WriteString("はっせん")
+	default:
+		sb.WriteString(digitReadings[th] + "せん")
+	}
+	switch h {
+	case 0:
+	case 1:
+		sb.WriteString("ひゃく")
+	case 3:
+		sb.WriteString("さんびゃく")
+	case 6:
+		sb.WriteString("ろっぴゃく")
+	case 8:
+		sb.WriteString("はっぴゃく")
+	default:
+		sb.WriteString(digitReadings[h] + "ひゃく")
+	}
+	switch t {
+	case 0:
+	case 1:
+		sb.WriteString("じゅう")
+	default:
+		sb.WriteString(digitReadings[t] + "じゅう")
+	}
+	if o > 0 {
+		sb.WriteString(digitReadings[o])
+	}
+	return sb.String()
+}
+
+var largeUnitReadings = []struct {
+	value   uint64
+	reading string
+}{
+	{1e16, "けい"},
+	{1e12, "ちょう"},
+	{1e8, "おく"},
+	{1e4, "まん"},
+}
+
+// ReadNumber returns the hiragana reading of n, e.g. 283 → にひゃくはちじゅうさん.
+// Units go up to 京 (10^16), which covers every int64.
+func ReadNumber(n int64) string {
+	if n == 0 {
+		return "ぜろ"
+	}
+	prefix := ""
+	// the magnitude as uint64, so that math.MinInt64 can be read too
+	m := uint64(n)
+	if n < 0 {
+		prefix = "まいなす"
+		m = uint64(-n)
+	}
+	var sb strings.Builder
+	for _, u := range largeUnitReadings {
+		g := int64(m / u.value)
+		if g == 0 {
+			continue
+		}
+		m %= u.value
+		gr := readGroup(g, true)
+		if u.reading == "ちょう" || u.reading == "けい" {
+			// いっちょう, はっちょう, じゅっちょう; いっけい, はっけい, じゅっけい
+			switch {
+			case g%10 == 1:
+				gr = strings.TrimSuffix(gr, "いち") + "いっ"
+			case g%10 == 8:
+				gr = strings.TrimSuffix(gr, "はち") + "はっ"
+			case g%10 == 0 && g%100 != 0 && strings.HasSuffix(gr, "じゅう"):
+				gr = strings.TrimSuffix(gr, "じゅう") + "じゅっ"
+			}
+		}
+		if g == 1 {
+			gr = "いち"
+			if u.reading == "ちょう" || u.reading == "けい" {
+				gr = "いっ"
+			}
+		}
+		sb.WriteString(gr + u.reading)
+	}
+	if m > 0 {
+		sb.WriteString(readGroup(int64(m), false))
+	}
+	return prefix + sb.String()
+}
+
+// Reading is the reading of a number+counter span, split into the part that sits over
+// the numeral and the part over the counter. Counter is empty when the whole span has
+// a single irregular reading (ひとり, ついたち, はたち).
+type Reading struct {
+	Number  string `json:"number"`
+	Counter string `json:"counter,omitempty"`
+}
+
+// String returns the full hiragana reading.
+func (r Reading) String() string {
+	return r.Number + r.Counter
+}
+
+// ReadWithCounter reads n followed by counter, applying gemination and voicing at the
+// boundary (はっぽん, よんじゅっぷん, さんびゃく) and counter-specific readings
+// (ひとり, よにん, ついたち). The bool is false if counter is not a known counter, in
+// which case only the number is read.
+func ReadWithCounter(n int64, counter string) (Reading, bool) {
+	c, ok := counters[counter]
+	if !ok {
+		return Reading{Number: ReadNumber(n)}, false
+	}
+	if whole, ok := c.special[n]; ok {
+		return Reading{Number: whole}, true
+	}
+	if n < 0 {
+		return Reading{Number: ReadNumber(n), Counter: c.reading}, true
+	}
+
+	full := ReadNumber(n)
+	stem, final, kind := splitFinal(n, full)
+	if kind < 10 {
+		if r, ok := c.digits[int(kind)]; ok {
+			final = r
+		}
+	}
+	number, counterReading := applySoundChange(final, kind, c)
+	return Reading{Number: stem + number, Counter: counterReading}, true
+}
+
+// splitFinal splits the reading of n into the part before its last element and the last
+// element itself. kind is the digit (1-9) or the unit value (10, 100, 1000, 10000, ...)
+// of that element.
+func splitFinal(n int64, full string) (stem, final string, kind int64) {
+	switch {
+	case n%10 != 0:
+		kind = n % 10
+		final = digitReadings[kind]
+	case n%100 != 0:
+		kind, final = 10, "じゅう"
+	case n%1000 != 0:
+		kind = 100
+		for _, f := range []string{"ひゃく", "びゃく", "ぴゃく"} {
+			if strings.HasSuffix(full, f) {
+				final = f
+			}
+		}
+	case n%10000 != 0:
+		kind = 1000
+		for _, f := range []string{"せん", "ぜん"} {
+			if strings.HasSuffix(full, f) {
+				final = f
+			}
+		}
+	default:
+		for _, u := range largeUnitReadings {
+			if strings.HasSuffix(full, u.reading) {
+				kind, final = int64(u.value), u.reading
+				break
+			}
+		}
+	}
+	return strings.TrimSuffix(full, final), final, kind
+}
+
+// applySoundChange joins the last element of a number with a counter.
+func applySoundChange(final string, kind int64, c counter) (string, string) {
+	geminate := func() string {
+		// drop the last mora and replace it with っ: いち→いっ, じゅう→じゅっ, ひゃく→ひゃっ
+		r := []rune(final)
+		return string(r[:len(r)-1]) + "っ"
+	}
+	switch c.class {
+	case classH:
+		switch {
+		case kind == 1, kind == 6, kind == 8, kind == 10, kind == 100:
+			return geminate(), handakuten(c.reading)
+		case kind == 3 || kind == 1000 || kind >= 10000:
+			if c.voiced3 {
+				return final, dakuten(c.reading)
+			}
+			return final, handakuten(c.reading)
+		case kind == 4 && c.handakuten4:
+			return final, handakuten(c.reading)
+		}
+	case classK:
+		switch {
+		case kind == 1, kind == 6, kind == 8, kind == 10, kind == 100:
+			return geminate(), c.reading
+		case kind == 3 && c.voiced3:
+			return final, dakuten(c.reading)
+		}
+	case classS, classT:
+		switch {
+		case kind == 1, kind == 8, kind == 10:
+			return geminate(), c.reading
+		case kind == 3 && c.voiced3:
+			return final, dakuten(c.reading)
+		}
+	}
+	return final, c.reading
+}
+
+var dakutenMap = map[rune]rune{
+	'か': 'が', 'き': 'ぎ', 'く': 'ぐ', 'け': 'げ', 'こ': 'ご',
+	'さ': 'ざ', 'し': 'じ', 'す': 'ず', 'せ': 'ぜ', 'そ': 'ぞ',
+	'た': 'だ', 'ち': 'ぢ', 'つ': 'づ', 'て': 'で', 'と': 'ど',
+	'は': 'ば', 'ひ': 'び', 'ふ': 'ぶ', 'へ': 'べ', 'ほ': 'ぼ',
+}
+
+var handakutenMap = map[rune]rune{'は': 'ぱ', 'ひ': 'ぴ', 'ふ': 'ぷ', 'へ': 'ぺ', 'ほ': 'ぽ'}
+
+func dakuten(s string) string {
+	return replaceFirst(s, dakutenMap)
+}
+
+func handakuten(s string) string {
+	return replaceFirst(s, handakutenMap)
+}
+
+func replaceFirst(s string, m map[rune]rune) string {
+	r := []rune(s)
+	if len(r) > 0 {
+		if v, ok := m[r[0]]; ok {
+			r[0] = v
+		}
+	}
+	return string(r)
+}
+
+// ToKatakana converts hiragana to katakana, the form kagome uses for Token.Reading.
+func ToKatakana(s string) string {
+	r := []rune(s)
+	for i, c := range r {
+		if c >= 0x3041 && c <= 0x3096 {
+			r[i] = c + 0x60
+		}
+	}
+	return string(r)
+}
+
+// Pronunciation converts a hiragana reading to the katakana pronunciation form kagome
+// uses for Token.Pronunciation, where long vowels are written with ー (ジュウ → ジュー).
+func Pronunciation(s string) string {
+	r := []rune(ToKatakana(s))
+	out := make([]rune, 0, len(r))
+	for i, c := range r {
+		if i > 0 && ((c == 'ウ' && (vowelOf(r[i-1]) == 'o' || vowelOf(r[i-1]) == 'u')) ||
+			(c == 'イ' && vowelOf(r[i-1]) == 'e')) {
+			out = append(out, 'ー')
+			continue
+		}
+		out = append(out, c)
+	}
+	return string(out)
+}
+
+// vowelOf returns the vowel of a katakana mora ('a', 'i', 'u', 'e', 'o') or 0.
+func vowelOf(c rune) rune {
+	rows := map[rune]string{
+		'a': "アカガサザタダナハバパマヤャラワ",
+		'i': "イキギシジチヂニヒビピミリ",
+		'u': "ウクグスズツヅヌフブプムユュル",
+		'e': "エケゲセゼテデネヘベペメレ",
+		'o': "オコゴソゾトドノホボポモヨョロヲ",
+	}
+	for v, row := range rows {
+		if strings.ContainsRune(row, c) {
+			return v
+		}
+	}
+	return 0
+}
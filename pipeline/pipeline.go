@@ -0,0 +1,347 @@
+// Package pipeline runs sentences through a chain of registered stages (ingest,
+// tokenize, merge, dictionary, furigana, analyze by default). Each stage has its own
+// pool of workers, and every submitted sentence gets a Future for its own result, so
+// callers never have to scan a shared channel for their sentence.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"japaneseparse/analyze"
+	"japaneseparse/ingest"
+	"japaneseparse/model"
+)
+
+// Result is the state of one sentence as it moves through the stages. Each stage
+// fills in its part.
+type Result struct {
+	Sentence ingest.Sentence         `json:"sentence"`
+	Tokens   []model.Token           `json:"tokens,omitempty"`   // tokenizer output
+	Merged   []model.Token           `json:"merged,omitempty"`   // after merging, enriched by later stages
+	Entries  []model.DictionaryEntry `json:"entries,omitempty"`  // dictionary entry per merged token
+	Lex      []model.LexEntry        `json:"lex,omitempty"`      // lexicon entries used by analysis
+	Analysis *analyze.Analysis       `json:"analysis,omitempty"` // set by the analyze stage
+	// Cached is set when the result came from a cache; the remaining stages are skipped.
+	Cached bool `json:"cached,omitempty"`
+}
+
+// StageFunc processes one sentence in place.
+type StageFunc func(ctx context.Context, r *Result) error
+
+// Stage is a named step with its own number of workers.
+type Stage struct {
+	Name    string
+	Workers int
+	Run     StageFunc
+}
+
+// ErrClosed is returned when submitting to a pipeline that was closed or whose
+// context is done.
+var ErrClosed = errors.New("pipeline closed")
+
+// job carries a result through the stages together with the submitter's context and
+// the future to resolve.
+type job struct {
+	ctx    context.Context
+	result *Result
+	future *Future
+}
+
+// Pipeline connects stages with channels. Create it with New, then Start it.
+type Pipeline struct {
+	stages []Stage
+	buffer int
+
+	mu      sync.RWMutex
+	in      chan job
+	closed  bool
+	ctx     context.Context
+	wg      sync.WaitGroup
+	started bool
+}
+
+// New builds a pipeline from stages in order. buffer is the capacity of the channel in
+// front of every stage.
+func New(buffer int, stages ...Stage) *Pipeline {
+	for i := range stages {
+		if stages[i].Workers < 1 {
+			stages[i].Workers = 1
+		}
+	}
+	return &Pipeline{stages: stages, buffer: buffer}
+}
+
+// Stages returns the registered stages.
+func (p *Pipeline) Stages() []Stage {
+	return append([]Stage(nil), p.stages...)
+}
+
+// Start launches the workers of every stage. When ctx is done every stage stops and
+// pending futures fail with the context error.
+func (p *Pipeline) Start(ctx context.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.started {
+		return
+	}
+	p.started = true
+	p.ctx = ctx
+	p.in = make(chan job, p.buffer)
+
+	in := p.in
+	for _, st := range p.stages {
+		out := make(chan job, p.buffer)
+		var stageWG sync.WaitGroup
+		for w := 0; w < st.Workers; w++ {
+			stageWG.Add(1)
+			p.wg.Add(1)
+			go func(st Stage, in <-chan job, out chan<- job) {
+				defer p.wg.Done()
+				defer stageWG.Done()
+				runStage(ctx, st, in, out)
+			}(st, in, out)
+		}
+		// close the next stage's input once every worker of this stage is done
+		go func(out chan job) {
+			stageWG.Wait()
+			close(out)
+		}(out)
+		in = out
+	}
+	// the last channel delivers finished results; they are acknowledged in the ingest
+	// backend so a durable queue does not replay them
+	p.wg.Add(1)
+	go func(done <-chan job) {
+		defer p.wg.Done()
+		for j := range done {
+			if err := ingest.Ack(j.result.Sentence.ID); err != nil {
+				log.Printf("[pipeline] ack failed for sentence %s: %v", j.result.Sentence.ID, err)
+			}
+			j.future.resolve(j.result, nil)
+		}
+	}(in)
+}
+
+// runStage is the loop of one worker. It returns when in is closed or ctx is done.
+func runStage(ctx context.Context, st Stage, in <-chan job, out chan<- job) {
+	for {
+		var j job
+		var ok bool
+		select {
+		case j, ok = <-in:
+			if !ok {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+		if err := firstErr(ctx.Err(), j.ctx.Err()); err != nil {
+			j.future.resolve(j.result, err)
+			continue
+		}
+		if j.result.Cached {
+			// nothing left to compute; pass the result along
+		} else if err := st.Run(j.ctx, j.result); err != nil {
+			id := j.result.Sentence.ID
+			log.Printf("[pipeline] stage %s failed for sentence %s: %v", st.Name, id, err)
+			err = fmt.Errorf("%s: %w", st.Name, err)
+			// a failing sentence would fail again; keep it out of the replay. An
+			// interrupted one would not, so it stays pending.
+			if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+				if ferr := ingest.Fail(id, err); ferr != nil {
+					log.Printf("[pipeline] recording failure of sentence %s: %v", id, ferr)
+				}
+			}
+			j.future.resolve(j.result, err)
+			continue
+		}
+		select {
+		case out <- j:
+		case <-ctx.Done():
+			j.future.resolve(j.result, ctx.Err())
+		case <-j.ctx.Done():
+			j.future.resolve(j.result, j.ctx.Err())
+		}
+	}
+}
+
+func firstErr(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Submit records s in the ingest backend and sends it into the pipeline, returning a
+// Future for its result. It blocks while the first stage is full. Cancelling ctx
+// abandons the sentence at the next stage boundary and fails its future; abandoned
+// sentences stay pending in the backend and are replayed after a restart. Sentences
+// without an ID (see SubmitText) get theirs in the ingest stage and are not recorded.
+func (p *Pipeline) Submit(ctx context.Context, s ingest.Sentence) (*Future, error) {
+	return p.submit(ctx, s, s.ID != "")
+}
+
+// Resubmit sends s into the pipeline like Submit without recording it again, for
+// sentences that are already in the backend, such as those from ingest.Pending.
+func (p *Pipeline) Resubmit(ctx context.Context, s ingest.Sentence) (*Future, error) {
+	return p.submit(ctx, s, false)
+}
+
+func (p *Pipeline) submit(ctx context.Context, s ingest.Sentence, record bool) (*Future, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if !p.started || p.closed {
+		return nil, ErrClosed
+	}
+	if record {
+		if err := ingest.Record(s); err != nil {
+			return nil, err
+		}
+	}
+	f := newFuture(p.ctx)
+	j := job{ctx: ctx, result: &Result{Sentence: s}, future: f}
+	select {
+	case p.in <- j:
+		return f, nil
+	case <-ctx.Done():
+		err := ctx.Err()
+		p.forget(s, record)
+		return nil, err
+	case <-p.ctx.Done():
+		p.forget(s, record)
+		return nil, ErrClosed
+	}
+}
+
+// forget acknowledges a recorded sentence that never entered the pipeline; the
+// caller got an error for it, so there is nothing to replay.
+func (p *Pipeline) forget(s ingest.Sentence, recorded bool) {
+	if !recorded {
+		return
+	}
+	if err := ingest.Ack(s.ID); err != nil {
+		log.Printf("[pipeline] ack failed for sentence %s: %v", s.ID, err)
+	}
+}
+
+// SubmitText submits raw text; the ingest stage normalizes it into a Sentence.
+func (p *Pipeline) SubmitText(ctx context.Context, text string) (*Future, error) {
+	return p.Submit(ctx, ingest.Sentence{Text: text})
+}
+
+// SubmitFunc submits s and calls fn with its result from another goroutine.
+func (p *Pipeline) SubmitFunc(ctx context.Context, s ingest.Sentence, fn func(*Result, error)) error {
+	f, err := p.Submit(ctx, s)
+	if err != nil {
+		return err
+	}
+	go func() {
+		fn(f.Wait(context.Background()))
+	}()
+	return nil
+}
+
+// Consume feeds the ingest queue (see ingest.Submit, ingest.IngestText and
+// ingest.IngestFile) into the pipeline until ctx is done or the pipeline closes,
+// calling fn with each result in queue order from another goroutine. The sentences are
+// already recorded in the ingest backend, so they are not recorded again; one taken
+// from the queue when the pipeline stops stays pending there. Consume returns after fn
+// has been called for every sentence it submitted.
+func (p *Pipeline) Consume(ctx context.Context, fn func(*Result, error)) error {
+	p.mu.RLock()
+	stop, started := p.ctx, p.started
+	p.mu.RUnlock()
+	if !started {
+		return ErrClosed
+	}
+	futures := make(chan *Future, p.buffer)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for f := range futures {
+			fn(f.Wait(context.Background()))
+		}
+	}()
+	defer func() {
+		close(futures)
+		<-done
+	}()
+	for {
+		select {
+		case s := <-ingest.Queue():
+			f, err := p.Resubmit(ctx, s)
+			if err != nil {
+				return err
+			}
+			futures <- f
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-stop.Done():
+			return ErrClosed
+		}
+	}
+}
+
+// Close stops accepting sentences and waits until every submitted sentence has left
+// the pipeline.
+func (p *Pipeline) Close() {
+	p.mu.Lock()
+	if !p.started || p.closed {
+		p.closed = true
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.in)
+	p.mu.Unlock()
+	p.wg.Wait()
+}
+
+// Future is the pending result of one submitted sentence.
+type Future struct {
+	done   chan struct{}
+	once   sync.Once
+	result *Result
+	err    error
+	stop   context.Context // the pipeline context; its end fails the future
+}
+
+func newFuture(stop context.Context) *Future {
+	return &Future{done: make(chan struct{}), stop: stop}
+}
+
+func (f *Future) resolve(r *Result, err error) {
+	f.once.Do(func() {
+		f.result, f.err = r, err
+		close(f.done)
+	})
+}
+
+// Done is closed when the result is available.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait blocks until the result is available, ctx is done or the pipeline is stopped.
+func (f *Future) Wait(ctx context.Context) (*Result, error) {
+	select {
+	case <-f.done:
+		return f.result, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-f.stop.Done():
+		// a result may have been delivered just before the pipeline stopped
+		select {
+		case <-f.done:
+			return f.result, f.err
+		default:
+		}
+		return nil, f.stop.Err()
+	}
+}
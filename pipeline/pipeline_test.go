@@ -0,0 +1,264 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"japaneseparse/cache"
+	"japaneseparse/ingest"
+	"japaneseparse/tokenize"
+)
+
+func TestFuturesResolvePerSentence(t *testing.T) {
+	p := NewDefault(Options{Workers: map[string]int{"tokenize": 4}})
+	p.Start(context.Background())
+	defer p.Close()
+
+	texts := []string{"今日は雨が降った。", "午前8時40分に283世帯へ避難を呼びかけた。", "ｶﾀｶﾅも読む。"}
+	futures := make([]*Future, len(texts))
+	for i, text := range texts {
+		f, err := p.SubmitText(context.Background(), text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		futures[i] = f
+	}
+	// wait in reverse order: each future carries only its own sentence
+	for i := len(futures) - 1; i >= 0; i-- {
+		r, err := futures[i].Wait(context.Background())
+		if err != nil {
+			t.Fatalf("sentence %d: %v", i, err)
+		}
+		if r.Sentence.Raw != texts[i] || r.Analysis == nil || len(r.Merged) == 0 {
+			t.Errorf("sentence %d: unexpected result %+v", i, r.Sentence)
+		}
+	}
+}
+
+func TestCancellationReachesStages(t *testing.T) {
+	block := make(chan struct{})
+	p := New(1,
+		Stage{Name: "slow", Run: func(ctx context.Context, r *Result) error {
+			select {
+			case <-block:
+			case <-ctx.Done():
+			}
+			return ctx.Err()
+		}},
+		Stage{Name: "never", Run: func(ctx context.Context, r *Result) error {
+			t.Error("stage after a cancelled one ran")
+			return nil
+		}},
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	p.Start(ctx)
+	f, err := p.SubmitText(context.Background(), "テスト")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err := f.Wait(context.Background()); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait after cancel = %v; want context.Canceled", err)
+	}
+	done := make(chan struct{})
+	go func() { p.Close(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Close did not return after cancellation")
+	}
+	if _, err := p.SubmitText(context.Background(), "後"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Submit after Close = %v; want ErrClosed", err)
+	}
+}
+
+func TestCacheSkipsStages(t *testing.T) {
+	c, err := cache.New(0, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tokenize.SetMode("normal")
+	run := func(mode string, created time.Time) *Result {
+		if err := tokenize.SetMode(mode); err != nil {
+			t.Fatal(err)
+		}
+		p := NewDefault(Options{Cache: c})
+		p.Start(context.Background())
+		defer p.Close()
+		s, _ := ingest.NewSentence("今日は雨が降った。")
+		s.CreatedAt = created
+		f, err := p.Submit(context.Background(), s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := f.Wait(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	day := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	first, second := run("normal", day), run("normal", day.AddDate(0, 0, 1))
+	if first.Cached || !second.Cached {
+		t.Fatalf("cached = %v, %v; want false, true", first.Cached, second.Cached)
+	}
+	if len(second.Merged) != len(first.Merged) || second.Analysis == nil || second.Analysis.SentenceID != second.Sentence.ID {
+		t.Errorf("cached result differs: %+v", second.Analysis)
+	}
+	// 今日 is resolved against each sentence's own time, cached or not
+	if tm := second.Analysis.Temporals; len(tm) != 1 || tm[0].Value != "2024-05-02" {
+		t.Errorf("cached temporals = %+v, want 今日 on 2024-05-02", tm)
+	}
+	if run("search", day).Cached {
+		t.Error("result cached under another tokenizer mode was reused")
+	}
+}
+
+func TestSubmitSettlesBackend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.log")
+	b, err := ingest.OpenFileBackend(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	ingest.SetBackend(b)
+	defer ingest.SetBackend(nil)
+
+	p := New(1, Stage{Name: "check", Run: func(ctx context.Context, r *Result) error {
+		if r.Sentence.Text == "bad" {
+			return errors.New("rejected")
+		}
+		return nil
+	}})
+	p.Start(context.Background())
+	defer p.Close()
+	for _, text := range []string{"good", "bad"} {
+		f, err := p.Submit(context.Background(), ingest.Sentence{ID: text, Text: text})
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Wait(context.Background())
+	}
+	if pending, _ := b.Pending(); len(pending) != 0 {
+		t.Errorf("pending after both settled: %v", pending)
+	}
+	dead, err := os.ReadFile(path + ".failed")
+	if err != nil || !strings.Contains(string(dead), `"id":"bad"`) || strings.Contains(string(dead), `"id":"good"`) {
+		t.Errorf("dead letters = %q, %v", dead, err)
+	}
+}
+
+func TestConsumeDrainsIngestQueue(t *testing.T) {
+	ingest.SetQueueSize(2)
+	defer ingest.SetQueueSize(ingest.DefaultQueueSize)
+	p := NewDefault(Options{})
+	p.Start(context.Background())
+	defer p.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results := make(chan *Result, 8)
+	consumed := make(chan error, 1)
+	go func() {
+		consumed <- p.Consume(ctx, func(r *Result, err error) {
+			if err != nil {
+				t.Error(err)
+			}
+			results <- r
+		})
+	}()
+
+	// more sentences than the queue holds: IngestText blocks unless Consume drains it
+	sentences, err := ingest.IngestText(context.Background(), "今日は雨。明日は晴れ。猫が寝た。犬が吠えた。本を読む。")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range sentences {
+		select {
+		case r := <-results:
+			if r == nil || r.Sentence.ID != s.ID || r.Analysis == nil {
+				t.Fatalf("result %d: got %+v, want sentence %s", i, r, s.ID)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("result %d never arrived", i)
+		}
+	}
+	cancel()
+	if err := <-consumed; !errors.Is(err, context.Canceled) {
+		t.Errorf("Consume = %v; want context.Canceled", err)
+	}
+}
+
+func TestCancelledSentenceIsReplayed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.log")
+	b, err := ingest.OpenFileBackend(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ingest.SetBackend(b)
+	defer ingest.SetBackend(nil)
+
+	started := make(chan struct{})
+	p := New(1, Stage{Name: "slow", Run: func(ctx context.Context, r *Result) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	p.Start(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	f, err := p.Submit(ctx, ingest.Sentence{ID: "s1", Text: "途中"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	cancel()
+	if _, err := f.Wait(context.Background()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Wait = %v; want context.Canceled", err)
+	}
+	p.Close()
+	b.Close()
+
+	// a new run over the same log still has the interrupted sentence to replay
+	b, err = ingest.OpenFileBackend(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if pending, _ := b.Pending(); len(pending) != 1 || pending[0].ID != "s1" {
+		t.Errorf("pending after cancel = %+v; want s1", pending)
+	}
+	if _, err := os.Stat(path + ".failed"); err == nil {
+		t.Error("cancelled sentence was dead-lettered")
+	}
+}
+
+func TestIngestSentenceWithConsumer(t *testing.T) {
+	ingest.SetQueueSize(1)
+	defer ingest.SetQueueSize(ingest.DefaultQueueSize)
+	p := New(1, Stage{Name: "noop", Run: func(ctx context.Context, r *Result) error { return nil }})
+	p.Start(context.Background())
+	defer p.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	results := make(chan *Result)
+	go p.Consume(ctx, func(r *Result, err error) { results <- r })
+
+	// more sentences than the queue holds; a consumer keeps TrySubmit from failing
+	dropped := ingest.Stats().Dropped
+	for i := 0; i < 5; i++ {
+		s, err := ingest.IngestSentence("雨が降った。")
+		if err != nil {
+			t.Fatalf("sentence %d: %v", i, err)
+		}
+		if r := <-results; r.Sentence.ID != s.ID {
+			t.Fatalf("sentence %d: got %s, want %s", i, r.Sentence.ID, s.ID)
+		}
+	}
+	if st := ingest.Stats(); st.Dropped != dropped || st.Queued != 0 {
+		t.Errorf("stats = %+v; want nothing dropped or left queued", st)
+	}
+}
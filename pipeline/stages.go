@@ -0,0 +1,248 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"japaneseparse/analyze"
+	"japaneseparse/cache"
+	"japaneseparse/dictionary"
+	"japaneseparse/ingest"
+	"japaneseparse/kanji"
+	"japaneseparse/lookup"
+	"japaneseparse/model"
+	"japaneseparse/tokenize"
+)
+
+// Options configures the default stages.
+type Options struct {
+	// Workers sets the worker count per stage name; stages not listed get one worker.
+	Workers map[string]int
+	// Profile limits furigana to words with kanji the reader does not know; nil keeps
+	// furigana everywhere.
+	Profile *kanji.ReaderProfile
+	// Cache, if set, adds a cache stage after ingest and a store stage at the end, so
+	// sentences seen before skip the other stages.
+	Cache *cache.Cache
+	// CacheConfig is added to cache keys to describe settings the pipeline cannot see.
+	// The dictionary and Kanjidic2 files, tokenizer mode, furigana aligner and
+	// normalization options are always part of the key.
+	CacheConfig string
+}
+
+// DefaultStages returns the standard stages: ingest, tokenize, merge, dictionary,
+// furigana and analyze, plus cache and store around them if opts.Cache is set.
+func DefaultStages(opts Options) []Stage {
+	stages := []Stage{
+		{Name: "ingest", Run: Ingest},
+		{Name: "tokenize", Run: Tokenize},
+		{Name: "merge", Run: Merge},
+		{Name: "dictionary", Run: Dictionary},
+		{Name: "furigana", Run: Furigana(opts.Profile)},
+		{Name: "analyze", Run: Analyze},
+	}
+	if opts.Cache != nil {
+		config := cacheConfig(stages, opts)
+		stages = append([]Stage{stages[0], {Name: "cache", Run: CacheLookup(opts.Cache, config)}}, stages[1:]...)
+		stages = append(stages, Stage{Name: "store", Run: CacheStore(opts.Cache, config)})
+	}
+	for i := range stages {
+		stages[i].Workers = opts.Workers[stages[i].Name]
+	}
+	return stages
+}
+
+// StagesUntil returns DefaultStages up to and including the stage named last, for
+// callers that need only part of the work. The cache stages are left out when the
+// list is cut short, so partial results are neither stored nor replaced by cached
+// full ones.
+func StagesUntil(opts Options, last string) []Stage {
+	all := DefaultStages(opts)
+	var out []Stage
+	for _, st := range all {
+		out = append(out, st)
+		if st.Name == last {
+			break
+		}
+	}
+	if len(out) == len(all) {
+		return out
+	}
+	kept := out[:0]
+	for _, st := range out {
+		if st.Name != "cache" {
+			kept = append(kept, st)
+		}
+	}
+	return kept
+}
+
+// Run passes r through stages in the calling goroutine, stopping at the first error or
+// when ctx is done. Like the pipeline, it skips the remaining stages of a cached
+// result.
+func Run(ctx context.Context, r *Result, stages []Stage) error {
+	for _, st := range stages {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if r.Cached {
+			return nil
+		}
+		if err := st.Run(ctx, r); err != nil {
+			return fmt.Errorf("%s: %w", st.Name, err)
+		}
+	}
+	return nil
+}
+
+// NewDefault builds a pipeline with DefaultStages.
+func NewDefault(opts Options) *Pipeline {
+	return New(ingest.DefaultQueueSize, DefaultStages(opts)...)
+}
+
+// Ingest turns raw submitted text (a Sentence with only Text set) into a normalized
+// Sentence. Sentences that were already built by the ingest package pass through.
+func Ingest(ctx context.Context, r *Result) error {
+	if r.Sentence.ID != "" {
+		return nil
+	}
+	s, err := ingest.NewSentence(r.Sentence.Text)
+	if err != nil {
+		return err
+	}
+	r.Sentence = s
+	return nil
+}
+
+// Tokenize runs the tokenizer over the sentence.
+func Tokenize(ctx context.Context, r *Result) error {
+	toks, err := tokenize.TokenizeSentence(ctx, r.Sentence)
+	if err != nil {
+		return err
+	}
+	r.Tokens = toks
+	return nil
+}
+
+// Merge merges verbs with their auxiliaries and numerals with their counters.
+func Merge(ctx context.Context, r *Result) error {
+	merged := tokenize.MergeVerbAuxiliaries(r.Tokens)
+	r.Merged = tokenize.MergeNumberCounters(merged)
+	return nil
+}
+
+// Dictionary looks up every merged token and attaches its entry.
+func Dictionary(ctx context.Context, r *Result) error {
+	entries, err := dictionary.LookupDictionary(ctx, r.Merged)
+	if err != nil {
+		return err
+	}
+	if len(entries) != len(r.Merged) {
+		return errors.New("dictionary returned a different number of entries than tokens")
+	}
+	for i := range r.Merged {
+		r.Merged[i].DictionaryEntry = entries[i]
+	}
+	r.Entries = entries
+	return nil
+}
+
+// Furigana returns a stage that updates furigana from the dictionary entries, limited
+// to words the reader profile does not know.
+func Furigana(profile *kanji.ReaderProfile) StageFunc {
+	return func(ctx context.Context, r *Result) error {
+		r.Merged = tokenize.UpdateFuriganaForProfile(r.Merged, profile)
+		return nil
+	}
+}
+
+// Analyze builds lexicon entries and runs sentence analysis.
+func Analyze(ctx context.Context, r *Result) error {
+	lex, err := lookup.Lookup(ctx, r.Merged)
+	if err != nil {
+		return err
+	}
+	r.Lex = lex
+	a, err := analyze.Analyze(ctx, r.Sentence, lex)
+	if err != nil {
+		return err
+	}
+	r.Analysis = &a
+	return nil
+}
+
+// cacheConfig describes the stages and options that shape a result.
+func cacheConfig(stages []Stage, opts Options) string {
+	names := make([]string, len(stages))
+	for i, st := range stages {
+		names[i] = st.Name
+	}
+	profile, _ := json.Marshal(opts.Profile)
+	return strings.Join(names, ",") + "|" + string(profile) + "|" + opts.CacheConfig
+}
+
+// cachedResult is what the cache keeps of a Result: everything but the sentence.
+type cachedResult struct {
+	Tokens   []model.Token           `json:"tokens,omitempty"`
+	Merged   []model.Token           `json:"merged,omitempty"`
+	Entries  []model.DictionaryEntry `json:"entries,omitempty"`
+	Lex      []model.LexEntry        `json:"lex,omitempty"`
+	Analysis *analyze.Analysis       `json:"analysis,omitempty"`
+}
+
+// cacheKey keys a sentence by its raw and normalized text (token offsets depend on
+// both), the pipeline configuration and the global settings that shape results: the
+// dictionary and Kanjidic2 files, the tokenizer mode, the furigana aligner and the
+// normalization options. The settings are read on every call, so changing one (as the
+// REPL's :mode does) never returns results made under the old one.
+func cacheKey(s ingest.Sentence, config string) string {
+	return cache.Key(s.Raw, s.Text, config,
+		"dictionary="+dictionary.Version(),
+		"kanjidic="+kanji.Version(),
+		"mode="+tokenize.Mode(),
+		"aligner="+tokenize.AlignerConfig(),
+		fmt.Sprintf("normalization=%+v", ingest.Normalization))
+}
+
+// CacheLookup returns a stage that fills in a result from c and marks it Cached. The
+// analysis is updated to refer to the current sentence, and its temporal expressions
+// are resolved again against the sentence's own time, since 昨日 depends on when it
+// was said.
+func CacheLookup(c *cache.Cache, config string) StageFunc {
+	return func(ctx context.Context, r *Result) error {
+		var cr cachedResult
+		if !c.Get(cacheKey(r.Sentence, config), &cr) {
+			return nil
+		}
+		r.Tokens, r.Merged, r.Entries, r.Lex, r.Analysis = cr.Tokens, cr.Merged, cr.Entries, cr.Lex, cr.Analysis
+		if r.Analysis != nil {
+			r.Analysis.SentenceID = r.Sentence.ID
+			r.Analysis.DocumentID = r.Sentence.DocumentID
+			r.Analysis.Paragraph = r.Sentence.Paragraph
+			r.Analysis.Index = r.Sentence.Index
+			toks := make([]model.Token, len(r.Lex))
+			for i, e := range r.Lex {
+				toks[i] = e.Token
+			}
+			r.Analysis.Temporals = analyze.ExtractTemporals(toks, r.Sentence.CreatedAt)
+		}
+		r.Cached = true
+		return nil
+	}
+}
+
+// CacheStore returns a stage that saves a finished result in c. A failed write is
+// logged and does not fail the sentence.
+func CacheStore(c *cache.Cache, config string) StageFunc {
+	return func(ctx context.Context, r *Result) error {
+		cr := cachedResult{Tokens: r.Tokens, Merged: r.Merged, Entries: r.Entries, Lex: r.Lex, Analysis: r.Analysis}
+		if err := c.Put(cacheKey(r.Sentence, config), cr); err != nil {
+			log.Printf("[pipeline] cache write failed for sentence %s: %v", r.Sentence.ID, err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,309 @@
+// Package server exposes the parser over HTTP. Every endpoint takes text either as a
+// JSON body ({"text": "..."}) or as a text query parameter, splits it into sentences and
+// answers with the model JSON shapes used elsewhere in the repository.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"japaneseparse/dictionary"
+	"japaneseparse/ingest"
+	"japaneseparse/kanji"
+	"japaneseparse/model"
+	"japaneseparse/pipeline"
+	"japaneseparse/subtitle"
+)
+
+const (
+	// DefaultMaxBodyBytes limits request bodies unless Options says otherwise.
+	DefaultMaxBodyBytes = 1 << 20
+	// DefaultTimeout limits the time spent on one request unless Options says otherwise.
+	DefaultTimeout = 30 * time.Second
+)
+
+// Options configures the server.
+type Options struct {
+	Addr         string
+	MaxBodyBytes int64         // larger bodies are rejected with 413
+	Timeout      time.Duration // requests still running after this get 503
+}
+
+// Request is the JSON body accepted by every endpoint. Only Text is required. Known,
+// Grade and JLPT describe the reader like the -known, -grade and -jlpt flags; words
+// made only of known kanji get no furigana.
+type Request struct {
+	Text   string `json:"text"`
+	Format string `json:"format,omitempty"` // furigana: html, aozora, ass or brackets
+	Known  string `json:"known,omitempty"`  // kanji the reader knows, e.g. "日本語"
+	Grade  int    `json:"grade,omitempty"`  // highest school grade known (1-6, 8 for all Joyo)
+	JLPT   int    `json:"jlpt,omitempty"`   // hardest JLPT level known (4 easiest, 1 hardest)
+}
+
+// profile builds the reader profile of the request; nil if none is set.
+func (req Request) profile() *kanji.ReaderProfile {
+	if req.Grade == 0 && req.JLPT == 0 && req.Known == "" {
+		return nil
+	}
+	p := kanji.NewKnownKanjiProfile(req.Known)
+	p.Grade, p.JLPT = req.Grade, req.JLPT
+	return p
+}
+
+// Response wraps the per-sentence results of an endpoint.
+type Response struct {
+	Sentences []*pipeline.Result `json:"sentences"`
+}
+
+// FuriganaSentence is one sentence of a furigana response.
+type FuriganaSentence struct {
+	SentenceID string        `json:"sentence_id"`
+	Text       string        `json:"text"`
+	Format     string        `json:"format"`
+	Furigana   string        `json:"furigana"`
+	Tokens     []model.Token `json:"tokens"`
+}
+
+// Health is the body of /health.
+type Health struct {
+	Status            string `json:"status"` // "ok", or "degraded" if a dictionary is missing
+	Dictionaries      bool   `json:"dictionaries_loaded"`
+	DictionaryVersion string `json:"dictionary_version"`
+	Kanjidic2Entries  int    `json:"kanjidic2_entries"`
+}
+
+// Server serves the HTTP API.
+type Server struct {
+	opts    Options
+	mux     *http.ServeMux
+	handler http.Handler // mux behind the request timeout
+}
+
+// New returns a server with defaults filled in for zero options.
+func New(opts Options) *Server {
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	s := &Server{opts: opts, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /health", s.health)
+	for path, h := range map[string]http.HandlerFunc{
+		"/tokenize": s.stages("tokenize"),
+		"/merged":   s.stages("merge"),
+		"/lookup":   s.stages("dictionary"),
+		"/analyze":  s.stages("analyze"),
+		"/furigana": s.furigana,
+		"/kanji":    s.kanji,
+	} {
+		s.mux.HandleFunc("GET "+path, h)
+		s.mux.HandleFunc("POST "+path, h)
+	}
+	s.handler = http.TimeoutHandler(s.mux, opts.Timeout, `{"error":"request timed out"}`)
+	return s
+}
+
+// ServeHTTP applies the request timeout and routes the request.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// ListenAndServe serves on opts.Addr until ctx is done, then shuts down gracefully.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:              s.opts.Addr,
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       s.opts.Timeout,
+		WriteTimeout:      s.opts.Timeout + 5*time.Second,
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	log.Printf("[server] Listening on %s", s.opts.Addr)
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), s.opts.Timeout)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	h := Health{
+		Status:            "ok",
+		Dictionaries:      dictionary.Loaded(),
+		DictionaryVersion: dictionary.Version(),
+		Kanjidic2Entries:  kanji.Count(),
+	}
+	status := http.StatusOK
+	if !h.Dictionaries || h.Kanjidic2Entries == 0 {
+		h.Status = "degraded"
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, h)
+}
+
+// stages returns a handler running the default stages up to and including last.
+func (s *Server) stages(last string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := s.readRequest(w, r)
+		if !ok {
+			return
+		}
+		results, err := run(r.Context(), req, last)
+		if err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, Response{Sentences: results})
+	}
+}
+
+func (s *Server) furigana(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.readRequest(w, r)
+	if !ok {
+		return
+	}
+	if req.Format == "" {
+		req.Format = string(subtitle.RubyHTML)
+	}
+	style, err := subtitle.ParseRubyStyle(req.Format)
+	if err != nil || style == subtitle.RubyNone {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown furigana format %q", req.Format))
+		return
+	}
+	results, err := run(r.Context(), req, "furigana")
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	profile := req.profile()
+	out := make([]FuriganaSentence, len(results))
+	for i, res := range results {
+		out[i] = FuriganaSentence{
+			SentenceID: res.Sentence.ID,
+			Text:       res.Sentence.Text,
+			Format:     req.Format,
+			Furigana:   subtitle.Ruby(res.Merged, style, profile),
+			Tokens:     res.Merged,
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string][]FuriganaSentence{"sentences": out})
+}
+
+func (s *Server) kanji(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.readRequest(w, r)
+	if !ok {
+		return
+	}
+	var out []kanji.Info
+	seen := make(map[rune]bool)
+	for _, c := range req.Text {
+		if kanji.IsKanji(c) && !seen[c] {
+			seen[c] = true
+			out = append(out, kanji.GetKanjiInfo(c))
+		}
+	}
+	if len(out) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("no kanji in text"))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]kanji.Info{"kanji": out})
+}
+
+// readRequest reads the JSON body of a POST or the query of a GET, writing an error
+// response and reporting false if it is unusable.
+func (s *Server) readRequest(w http.ResponseWriter, r *http.Request) (Request, bool) {
+	var req Request
+	if r.Method == http.MethodPost {
+		r.Body = http.MaxBytesReader(w, r.Body, s.opts.MaxBodyBytes)
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("body larger than %d bytes", tooLarge.Limit))
+			} else {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON body: %w", err))
+			}
+			return req, false
+		}
+	} else {
+		q := r.URL.Query()
+		req.Text, req.Format, req.Known = q.Get("text"), q.Get("format"), q.Get("known")
+		for name, dst := range map[string]*int{"grade": &req.Grade, "jlpt": &req.JLPT} {
+			if v := q.Get(name); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil {
+					writeError(w, http.StatusBadRequest, fmt.Errorf("invalid %s %q", name, v))
+					return req, false
+				}
+				*dst = n
+			}
+		}
+		if int64(len(req.Text)) > s.opts.MaxBodyBytes {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("text longer than %d bytes", s.opts.MaxBodyBytes))
+			return req, false
+		}
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("text is required"))
+		return req, false
+	}
+	return req, true
+}
+
+// run splits req.Text into sentences and runs each through the default stages up to
+// and including last.
+func run(ctx context.Context, req Request, last string) ([]*pipeline.Result, error) {
+	doc, err := ingest.NewDocument(req.Text, ingest.Metadata{Source: "http"})
+	if err != nil {
+		return nil, badRequest{err}
+	}
+	stages := pipeline.StagesUntil(pipeline.Options{Profile: req.profile()}, last)
+	var results []*pipeline.Result
+	for _, sentence := range doc.Sentences() {
+		res := &pipeline.Result{Sentence: sentence}
+		if err := pipeline.Run(ctx, res, stages); err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// badRequest marks errors caused by the input.
+type badRequest struct{ error }
+
+func statusOf(err error) int {
+	var br badRequest
+	switch {
+	case errors.As(err, &br):
+		return http.StatusBadRequest
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("[server] write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
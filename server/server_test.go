@@ -0,0 +1,78 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"japaneseparse/dictionary"
+)
+
+func TestEndpoints(t *testing.T) {
+	ts := httptest.NewServer(New(Options{MaxBodyBytes: 256}))
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/tokenize", "application/json", strings.NewReader(`{"text":"今日は雨が降った。明日は晴れる。"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tok Response
+	json.NewDecoder(resp.Body).Decode(&tok)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(tok.Sentences) != 2 || len(tok.Sentences[0].Tokens) == 0 {
+		t.Fatalf("tokenize: status %d, %+v", resp.StatusCode, tok)
+	}
+	if tok.Sentences[0].Analysis != nil {
+		t.Error("tokenize ran the analyze stage")
+	}
+
+	resp, err = http.Get(ts.URL + "/furigana?format=aozora&text=" + url.QueryEscape("雨が降った"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fur struct{ Sentences []FuriganaSentence }
+	json.NewDecoder(resp.Body).Decode(&fur)
+	resp.Body.Close()
+	if len(fur.Sentences) != 1 || !strings.Contains(fur.Sentences[0].Furigana, "｜雨《あめ》") {
+		t.Errorf("furigana: status %d, %+v", resp.StatusCode, fur)
+	}
+
+	resp, err = http.Post(ts.URL+"/furigana", "application/json", strings.NewReader(`{"text":"雨が降った","format":"aozora","known":"雨"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fur.Sentences = nil
+	json.NewDecoder(resp.Body).Decode(&fur)
+	resp.Body.Close()
+	if len(fur.Sentences) != 1 || strings.Contains(fur.Sentences[0].Furigana, "雨《") || !strings.Contains(fur.Sentences[0].Furigana, "降《") {
+		t.Errorf("furigana with known kanji: status %d, %+v", resp.StatusCode, fur)
+	}
+
+	big := `{"text":"` + strings.Repeat("あ", 200) + `"}`
+	resp, err = http.Post(ts.URL+"/analyze", "application/json", strings.NewReader(big))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body: status %d, want 413", resp.StatusCode)
+	}
+
+	// the dictionary files are missing in tests
+	if err := dictionary.InitDictionaries("testdata/missing_jmdict", "testdata/missing_enamdict"); err == nil {
+		t.Error("InitDictionaries succeeded without dictionary files")
+	}
+	resp, err = http.Get(ts.URL + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var h Health
+	json.NewDecoder(resp.Body).Decode(&h)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || h.Status != "degraded" || h.Dictionaries {
+		t.Errorf("health: status %d, %+v", resp.StatusCode, h)
+	}
+}
@@ -0,0 +1,322 @@
+package subtitle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"japaneseparse/dictionary"
+	"japaneseparse/furigana"
+	"japaneseparse/kanji"
+	"japaneseparse/model"
+	"japaneseparse/tokenize"
+)
+
+// RubyStyle selects how furigana is written into cue text.
+type RubyStyle string
+
+const (
+	RubyNone   RubyStyle = ""
+	RubyASS    RubyStyle = "ass"    // Aegisub karaoke furigana: {\k0}漢字|かんじ
+	RubyAozora RubyStyle = "aozora" // Aozora Bunko notation: ｜漢字《かんじ》
+	RubyHTML   RubyStyle = "html"   // WebVTT ruby: <ruby>漢字<rt>かんじ</rt></ruby>
+	// RubyBrackets is the plain-text display notation [漢字|かんじ], for terminals.
+	RubyBrackets RubyStyle = "brackets"
+)
+
+// ParseRubyStyle returns the style with the given name ("none" for RubyNone).
+func ParseRubyStyle(name string) (RubyStyle, error) {
+	switch style := RubyStyle(name); style {
+	case RubyASS, RubyAozora, RubyHTML, RubyBrackets:
+		return style, nil
+	case "none", "":
+		return RubyNone, nil
+	}
+	return RubyNone, fmt.Errorf("unknown ruby style %q (want html, aozora, ass, brackets or none)", name)
+}
+
+// DefaultRuby returns the ruby style native to a format: ASS karaoke furigana for ASS,
+// <ruby> for WebVTT and Aozora notation for SRT, which has no ruby markup.
+func DefaultRuby(format Format) RubyStyle {
+	switch format {
+	case ASS:
+		return RubyASS
+	case VTT:
+		return RubyHTML
+	}
+	return RubyAozora
+}
+
+// Options controls Process.
+type Options struct {
+	Ruby    RubyStyle            // furigana markup added to cue text; RubyNone leaves it out
+	Glosses bool                 // append a line of glosses for unknown words to each cue
+	Profile *kanji.ReaderProfile // words the reader knows get no furigana or glosses; nil knows nothing
+}
+
+// Word is a vocabulary item found in a cue.
+type Word struct {
+	Surface string   `json:"surface"`
+	Lemma   string   `json:"lemma"`
+	Reading string   `json:"reading,omitempty"` // hiragana reading of the surface
+	POS     string   `json:"pos"`
+	Glosses []string `json:"glosses,omitempty"`
+	Unknown bool     `json:"unknown,omitempty"` // contains kanji outside the reader profile
+}
+
+// CueVocab lists the words of one cue, for the vocabulary sidecar.
+type CueVocab struct {
+	Index int    `json:"index"`
+	ID    string `json:"id,omitempty"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+	Text  string `json:"text"`
+	Words []Word `json:"words"`
+}
+
+// Process tokenizes every cue, looks its words up and returns a copy of f with the cue
+// text annotated according to opts, together with the per-cue vocabulary. Timing and
+// all other parts of the file are unchanged.
+func Process(ctx context.Context, f *File, opts Options) (*File, []CueVocab, error) {
+	files, vocab, err := Annotate(ctx, f, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return files[0], vocab, nil
+}
+
+// Annotate is Process for several annotations of the same file: every cue is tokenized
+// and looked up once, and one copy of f is returned per entry of opts. The vocabulary
+// follows the profile of the first entry.
+func Annotate(ctx context.Context, f *File, opts ...Options) ([]*File, []CueVocab, error) {
+	files := make([]*File, len(opts))
+	for i := range files {
+		files[i] = f.Clone()
+	}
+	vocab := make([]CueVocab, 0, len(f.Cues))
+	for i, c := range f.Cues {
+		var cueLines [][]model.Token
+		for _, line := range strings.Split(c.Text, "\n") {
+			toks, err := analyzeLine(ctx, line)
+			if err != nil {
+				return nil, nil, fmt.Errorf("cue %d: %w", i+1, err)
+			}
+			cueLines = append(cueLines, toks)
+		}
+		for j, o := range opts {
+			text, words := annotateCue(cueLines, o)
+			files[j].Cues[i].Text = text
+			if j == 0 {
+				vocab = append(vocab, CueVocab{
+					Index: i + 1,
+					ID:    c.ID,
+					Start: formatDuration(c.Start),
+					End:   formatDuration(c.End),
+					Text:  c.Text,
+					Words: words,
+				})
+			}
+		}
+	}
+	return files, vocab, nil
+}
+
+// annotateCue returns the text of one cue, given the tokens of each of its lines,
+// annotated according to opts, together with its words.
+func annotateCue(cueLines [][]model.Token, opts Options) (string, []Word) {
+	var lines, glossLines []string
+	var words []Word
+	for _, toks := range cueLines {
+		var sb strings.Builder
+		for _, t := range toks {
+			sb.WriteString(rubyText(t, opts))
+			if w, ok := wordOf(t, opts.Profile); ok {
+				words = append(words, w)
+			}
+		}
+		lines = append(lines, sb.String())
+	}
+	if opts.Glosses {
+		seen := map[string]bool{}
+		var parts []string
+		for _, w := range words {
+			if !w.Unknown || len(w.Glosses) == 0 || seen[w.Lemma] {
+				continue
+			}
+			seen[w.Lemma] = true
+			parts = append(parts, fmt.Sprintf("%s(%s): %s", w.Lemma, w.Reading, strings.Join(w.Glosses, ", ")))
+		}
+		if len(parts) > 0 {
+			glossLines = append(glossLines, escape(strings.Join(parts, " / "), opts.Ruby))
+		}
+	}
+	return strings.Join(append(lines, glossLines...), "\n"), words
+}
+
+// WriteVocab writes the vocabulary sidecar as indented JSON.
+func WriteVocab(w io.Writer, vocab []CueVocab) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(vocab)
+}
+
+// analyzeLine tokenizes one line of cue text, merges numerals with counters and
+// attaches dictionary entries.
+func analyzeLine(ctx context.Context, line string) ([]model.Token, error) {
+	if strings.TrimSpace(line) == "" {
+		return []model.Token{{Text: line}}, nil
+	}
+	toks, err := tokenize.Tokenize(ctx, line)
+	if err != nil {
+		return nil, err
+	}
+	toks = tokenize.MergeNumberCounters(toks)
+	entries, err := dictionary.LookupDictionary(ctx, toks)
+	if err != nil {
+		return nil, err
+	}
+	for i := range toks {
+		if i < len(entries) {
+			toks[i].DictionaryEntry = entries[i]
+		}
+	}
+	return toks, nil
+}
+
+// Ruby returns the text of tokens with furigana in the given style, leaving out words
+// whose kanji the reader profile knows.
+func Ruby(tokens []model.Token, style RubyStyle, profile *kanji.ReaderProfile) string {
+	var sb strings.Builder
+	for _, t := range tokens {
+		sb.WriteString(rubyText(t, Options{Ruby: style, Profile: profile}))
+	}
+	return sb.String()
+}
+
+// rubyText returns the token text with furigana markup on its kanji, or the bare text if
+// no ruby is wanted or the reader knows every kanji of the word.
+func rubyText(t model.Token, opts Options) string {
+	if opts.Ruby == RubyNone || t.Reading == "" || !opts.Profile.NeedsFurigana(t.Text) {
+		return plainSegment(t.Text, opts.Ruby)
+	}
+	pairs := tokenize.GetFuriganaString(t.Text, t.Reading)
+	joined := ""
+	for _, p := range pairs {
+		joined += p[0]
+	}
+	if joined != t.Text {
+		return plainSegment(t.Text, opts.Ruby) // alignment did not cover the surface; never change the text
+	}
+	var sb strings.Builder
+	for _, g := range rubyGroups(pairs) {
+		if g[1] == "" {
+			sb.WriteString(plainSegment(g[0], opts.Ruby))
+			continue
+		}
+		reading := furigana.KatakanaToHiragana(g[1])
+		switch opts.Ruby {
+		case RubyASS:
+			sb.WriteString(`{\k0}` + g[0] + "|" + reading)
+		case RubyAozora:
+			sb.WriteString("｜" + g[0] + "《" + reading + "》")
+		case RubyHTML:
+			sb.WriteString("<ruby>" + g[0] + "<rt>" + reading + "</rt></ruby>")
+		case RubyBrackets:
+			sb.WriteString("[" + g[0] + "|" + reading + "]")
+		}
+	}
+	return sb.String()
+}
+
+// rubyGroups merges runs of adjacent kanji pairs in which some kanji got no reading into
+// one group carrying the whole reading, so a partial alignment never puts the reading
+// of 学校 over 校 alone. Empty-surface leftovers are dropped.
+func rubyGroups(pairs [][2]string) [][2]string {
+	var out [][2]string
+	for i := 0; i < len(pairs); {
+		if pairs[i][0] == "" {
+			i++
+			continue
+		}
+		if !furigana.IsKanji([]rune(pairs[i][0])[0]) {
+			out = append(out, pairs[i])
+			i++
+			continue
+		}
+		j, partial := i, false
+		var base, reading string
+		for j < len(pairs) && pairs[j][0] != "" && furigana.IsKanji([]rune(pairs[j][0])[0]) {
+			base += pairs[j][0]
+			reading += pairs[j][1]
+			partial = partial || pairs[j][1] == ""
+			j++
+		}
+		if partial {
+			out = append(out, [2]string{base, reading})
+		} else {
+			out = append(out, pairs[i:j]...)
+		}
+		i = j
+	}
+	return out
+}
+
+// plainSegment writes text without ruby. ASS karaoke furigana needs every segment to
+// start its own syllable, otherwise the text is read as part of the previous reading.
+func plainSegment(s string, style RubyStyle) string {
+	if style == RubyASS {
+		return `{\k0}` + s
+	}
+	return escape(s, style)
+}
+
+// escape escapes text placed next to HTML ruby markup.
+func escape(s string, style RubyStyle) string {
+	if style == RubyHTML {
+		return html.EscapeString(s)
+	}
+	return s
+}
+
+// contentPOS are the parts of speech that go into the vocabulary.
+var contentPOS = []string{"名詞", "動詞", "形容詞", "形容動詞", "副詞", "連体詞"}
+
+// wordOf returns the vocabulary item for a content-word token.
+func wordOf(t model.Token, profile *kanji.ReaderProfile) (Word, bool) {
+	if strings.HasPrefix(t.POS, "名詞,数") || strings.HasPrefix(t.POS, "名詞,非自立") ||
+		strings.HasPrefix(t.POS, "動詞,非自立") || strings.HasPrefix(t.POS, "名詞,代名詞") {
+		return Word{}, false
+	}
+	content := false
+	for _, p := range contentPOS {
+		if strings.HasPrefix(t.POS, p) {
+			content = true
+			break
+		}
+	}
+	if !content {
+		return Word{}, false
+	}
+	w := Word{
+		Surface: t.Text,
+		Lemma:   t.Lemma,
+		Reading: furigana.KatakanaToHiragana(t.Reading),
+		POS:     t.POS,
+		Unknown: profile.NeedsFurigana(t.Lemma),
+	}
+	if t.DictionaryEntry.Source != "none" {
+		w.Glosses = t.DictionaryEntry.Glosses
+	}
+	return w, true
+}
+
+// formatDuration formats d as HH:MM:SS.mmm.
+func formatDuration(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -0,0 +1,159 @@
+package subtitle
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	htmlTagRe = regexp.MustCompile(`<[^>]*>`)
+	assTagRe  = regexp.MustCompile(`\{[^}]*\}`)
+	// leading ASS override blocks such as {\an8}{\pos(320,50)}
+	assLeadingTagsRe = regexp.MustCompile(`^(?:\{[^}]*\})+`)
+	// leading SRT position tags and VTT voice spans, which need no closing tag
+	htmlLeadingTagsRe = regexp.MustCompile(`^(?:\{\\[^}]*\}|<v[ .][^>]*>)+`)
+)
+
+// parseBlocks parses SRT and WebVTT, which are both blank-line separated blocks. Blocks
+// without a timing line (the WEBVTT header, NOTE, STYLE and REGION blocks) are kept raw.
+func parseBlocks(s string, format Format) (*File, error) {
+	f := &File{Format: format, sep: "\n\n"}
+	for _, block := range splitBlocks(s) {
+		lines := strings.Split(block, "\n")
+		t := -1
+		for i, l := range lines {
+			if strings.Contains(l, "-->") {
+				t = i
+				break
+			}
+		}
+		if t < 0 || t > 1 || strings.HasPrefix(block, "NOTE") {
+			f.items = append(f.items, item{raw: block, cue: -1})
+			continue
+		}
+		c := Cue{Timing: lines[t]}
+		if t == 1 {
+			c.ID = lines[0]
+		}
+		times := strings.SplitN(lines[t], "-->", 2)
+		start, err := parseTimestamp(times[0])
+		if err != nil {
+			return nil, err
+		}
+		// VTT settings may follow the end time: "00:01.000 --> 00:02.000 align:start"
+		endField := strings.Fields(times[1])
+		if len(endField) == 0 {
+			return nil, fmt.Errorf("%s: missing end time in %q", format, lines[t])
+		}
+		end, err := parseTimestamp(endField[0])
+		if err != nil {
+			return nil, err
+		}
+		c.Start, c.End = start, end
+		c.raw = strings.Join(lines[t+1:], "\n")
+		c.Tags = htmlLeadingTagsRe.FindString(c.raw)
+		c.Text = plainHTML(c.raw)
+		c.plain = c.Text
+		f.items = append(f.items, item{cue: len(f.Cues)})
+		f.Cues = append(f.Cues, c)
+	}
+	return f, nil
+}
+
+// splitBlocks splits s at blank lines, dropping empty blocks.
+func splitBlocks(s string) []string {
+	var blocks []string
+	var cur []string
+	for _, l := range strings.Split(s, "\n") {
+		if strings.TrimSpace(l) == "" {
+			if len(cur) > 0 {
+				blocks = append(blocks, strings.Join(cur, "\n"))
+				cur = nil
+			}
+			continue
+		}
+		cur = append(cur, l)
+	}
+	if len(cur) > 0 {
+		blocks = append(blocks, strings.Join(cur, "\n"))
+	}
+	return blocks
+}
+
+// plainHTML strips the HTML-like tags used in SRT and VTT (<i>, <v Speaker>, <c.red>),
+// SRT position tags such as {\an8}, and unescapes entities.
+func plainHTML(s string) string {
+	s = htmlTagRe.ReplaceAllString(s, "")
+	s = assTagRe.ReplaceAllString(s, "")
+	return html.UnescapeString(s)
+}
+
+// parseASS parses an ASS/SSA script. Only Dialogue lines of the [Events] section become
+// cues; every other line is kept raw.
+func parseASS(s string) (*File, error) {
+	f := &File{Format: ASS, sep: "\n"}
+	inEvents := false
+	var fields []string
+	for _, line := range strings.Split(strings.TrimRight(s, "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			inEvents = strings.EqualFold(trimmed, "[Events]")
+		}
+		if inEvents && strings.HasPrefix(trimmed, "Format:") {
+			fields = strings.Split(strings.TrimPrefix(trimmed, "Format:"), ",")
+			for i := range fields {
+				fields[i] = strings.TrimSpace(fields[i])
+			}
+		}
+		if !inEvents || !strings.HasPrefix(line, "Dialogue:") || len(fields) == 0 {
+			f.items = append(f.items, item{raw: line, cue: -1})
+			continue
+		}
+		c, err := parseDialogue(line, fields)
+		if err != nil {
+			return nil, err
+		}
+		f.items = append(f.items, item{cue: len(f.Cues)})
+		f.Cues = append(f.Cues, c)
+	}
+	return f, nil
+}
+
+// parseDialogue splits a Dialogue line by the Format fields. Text is the last field and
+// may itself contain commas.
+func parseDialogue(line string, fields []string) (Cue, error) {
+	body := strings.TrimPrefix(line, "Dialogue:")
+	values := strings.SplitN(body, ",", len(fields))
+	if len(values) != len(fields) {
+		return Cue{}, fmt.Errorf("ass: dialogue has %d fields, want %d: %q", len(values), len(fields), line)
+	}
+	c := Cue{}
+	for i, name := range fields {
+		var err error
+		switch name {
+		case "Start":
+			c.Start, err = parseTimestamp(values[i])
+		case "End":
+			c.End, err = parseTimestamp(values[i])
+		}
+		if err != nil {
+			return Cue{}, err
+		}
+	}
+	text := values[len(values)-1]
+	c.Timing = line[:len(line)-len(text)]
+	c.raw = text
+	c.Tags = assLeadingTagsRe.FindString(text)
+	c.Text = plainASS(text)
+	c.plain = c.Text
+	return c, nil
+}
+
+// plainASS strips override blocks and converts ASS line breaks and hard spaces.
+func plainASS(s string) string {
+	s = assTagRe.ReplaceAllString(s, "")
+	s = strings.NewReplacer(`\N`, "\n", `\n`, "\n", `\h`, " ").Replace(s)
+	return s
+}
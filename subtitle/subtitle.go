@@ -0,0 +1,205 @@
+// Package subtitle reads and writes SRT, WebVTT and ASS subtitle files. Cue timing,
+// headers, styles and everything else outside the cue text are written back unchanged,
+// so a processed file lines up with the original video.
+package subtitle
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"japaneseparse/ingest"
+)
+
+// Format is a subtitle file format.
+type Format string
+
+const (
+	SRT Format = "srt"
+	VTT Format = "vtt"
+	ASS Format = "ass"
+)
+
+// Cue is one subtitle. Timing holds the raw timing line (SRT/VTT) or the raw Dialogue
+// fields before the text (ASS) and is written back verbatim.
+type Cue struct {
+	ID     string        `json:"id,omitempty"`
+	Timing string        `json:"-"`
+	Start  time.Duration `json:"start"`
+	End    time.Duration `json:"end"`
+	Text   string        `json:"text"` // plain text, markup removed, lines joined with "\n"
+	Tags   string        `json:"-"`    // markup kept at the start of rewritten text, e.g. {\an8} or <v Ken>
+
+	raw   string // cue text as found in the file
+	plain string // Text as parsed, to detect edits
+}
+
+// File is a parsed subtitle file.
+type File struct {
+	Format   Format
+	Encoding ingest.Encoding // encoding the file was decoded from
+	Cues     []Cue
+
+	items []item // file layout: raw blocks/lines and cues in order
+	sep   string // separator between items: "\n\n" for SRT/VTT blocks, "\n" for ASS lines
+}
+
+// item is either a raw block of the file or a reference to a cue.
+type item struct {
+	raw string
+	cue int // index into Cues, -1 for raw items
+}
+
+// FormatFromPath returns the format implied by a file extension.
+func FormatFromPath(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".srt":
+		return SRT, nil
+	case ".vtt":
+		return VTT, nil
+	case ".ass", ".ssa":
+		return ASS, nil
+	}
+	return "", fmt.Errorf("unknown subtitle format: %s", path)
+}
+
+// Parse reads a subtitle file in the given format. The bytes are decoded with
+// ingest.InputEncoding, so Shift_JIS and EUC-JP subtitles are detected by default.
+func Parse(r io.Reader, format Format) (*File, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	s, enc, err := ingest.Decode(b, ingest.InputEncoding)
+	if err != nil {
+		return nil, err
+	}
+	s = strings.TrimPrefix(s, "\ufeff")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	var f *File
+	switch format {
+	case SRT, VTT:
+		f, err = parseBlocks(s, format)
+	case ASS:
+		f, err = parseASS(s)
+	default:
+		return nil, fmt.Errorf("unknown subtitle format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	f.Encoding = enc
+	return f, nil
+}
+
+// ParseFile reads a subtitle file, picking the format from its extension.
+func ParseFile(path string) (*File, error) {
+	format, err := FormatFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f, format)
+}
+
+// Write writes the file back as UTF-8. Cues whose Text was not changed keep their
+// original markup; changed cues are written from Text.
+func (f *File) Write(w io.Writer) error {
+	_, err := io.WriteString(w, f.String())
+	return err
+}
+
+// WriteEncoded writes the file in enc, e.g. Shift_JIS for older players, and reports
+// the characters enc cannot represent; they are written as '?'.
+func (f *File) WriteEncoded(w io.Writer, enc ingest.Encoding) ([]ingest.Unencodable, error) {
+	b, bad, err := ingest.Encode(f.String(), enc)
+	if err != nil {
+		return bad, err
+	}
+	_, err = w.Write(b)
+	return bad, err
+}
+
+// String returns the file contents.
+func (f *File) String() string {
+	parts := make([]string, len(f.items))
+	for i, it := range f.items {
+		if it.cue < 0 {
+			parts[i] = it.raw
+			continue
+		}
+		parts[i] = f.formatCue(f.Cues[it.cue])
+	}
+	return strings.Join(parts, f.sep) + "\n"
+}
+
+// WriteFile writes the file to path.
+func (f *File) WriteFile(path string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := f.Write(out); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Clone returns a copy of f whose cues can be edited independently.
+func (f *File) Clone() *File {
+	c := *f
+	c.Cues = append([]Cue(nil), f.Cues...)
+	return &c
+}
+
+// formatCue renders one cue in the file's format.
+func (f *File) formatCue(c Cue) string {
+	text := c.raw
+	if c.Text != c.plain {
+		text = c.Text
+		if f.Format == ASS {
+			text = strings.ReplaceAll(text, "\n", `\N`)
+		}
+		text = c.Tags + text
+	}
+	switch f.Format {
+	case ASS:
+		return c.Timing + text
+	default:
+		var sb strings.Builder
+		if c.ID != "" {
+			sb.WriteString(c.ID + "\n")
+		}
+		sb.WriteString(c.Timing + "\n" + text)
+		return sb.String()
+	}
+}
+
+// parseTimestamp parses SRT (00:01:02,500), VTT (01:02.500, 00:01:02.500) and ASS
+// (0:01:02.50) timestamps.
+func parseTimestamp(s string) (time.Duration, error) {
+	fields := strings.Split(strings.TrimSpace(strings.Replace(s, ",", ".", 1)), ":")
+	if len(fields) == 2 {
+		fields = append([]string{"0"}, fields...)
+	}
+	if len(fields) != 3 {
+		return 0, fmt.Errorf("bad timestamp %q", s)
+	}
+	h, err1 := strconv.Atoi(fields[0])
+	m, err2 := strconv.Atoi(fields[1])
+	sec, err3 := strconv.ParseFloat(fields[2], 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return 0, fmt.Errorf("bad timestamp %q", s)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute +
+		time.Duration(sec*float64(time.Second)+0.5), nil
+}
@@ -0,0 +1,94 @@
+package subtitle
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleASS = `[Script Info]
+Title: test
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Dialogue: 0,0:00:01.00,0:00:03.50,Default,,0,0,0,,{\an8}雨が、\N降った。
+Comment: 0,0:00:04.00,0:00:05.00,Default,,0,0,0,,メモ`
+
+func TestRoundTripKeepsTiming(t *testing.T) {
+	srt := "1\n00:00:01,000 --> 00:00:03,500\n<i>雨だ。</i>\n\n2\n00:00:04,000 --> 00:00:06,000\n晴れ"
+	cases := []struct {
+		format Format
+		in     string
+	}{
+		{SRT, srt},
+		{VTT, "WEBVTT\n\nc1\n00:01.000 --> 00:03.500 align:start\n<v Ken>雨だ"},
+		{ASS, sampleASS},
+	}
+	for _, c := range cases {
+		f, err := Parse(strings.NewReader(c.in), c.format)
+		if err != nil {
+			t.Fatalf("%s: %v", c.format, err)
+		}
+		if f.Cues[0].Start != time.Second || f.Cues[0].End != 3500*time.Millisecond {
+			t.Errorf("%s: cue timing %v-%v", c.format, f.Cues[0].Start, f.Cues[0].End)
+		}
+		var sb strings.Builder
+		f.Write(&sb)
+		if got := strings.TrimSuffix(sb.String(), "\n"); got != c.in {
+			t.Errorf("%s: round trip changed the file:\n%s", c.format, got)
+		}
+	}
+}
+
+func TestEditedASSCueKeepsTagsAndTiming(t *testing.T) {
+	f, err := Parse(strings.NewReader(sampleASS), ASS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.Cues[0].Text; got != "雨が、\n降った。" {
+		t.Fatalf("plain text = %q", got)
+	}
+	f.Cues[0].Text = "｜雨《あめ》が、\n降った。"
+	var sb strings.Builder
+	f.Write(&sb)
+	want := `Dialogue: 0,0:00:01.00,0:00:03.50,Default,,0,0,0,,{\an8}｜雨《あめ》が、\N降った。`
+	if !strings.Contains(sb.String(), want) {
+		t.Errorf("edited cue not written as %q:\n%s", want, sb.String())
+	}
+}
+
+func TestRubyGroupsMergePartialAlignment(t *testing.T) {
+	got := rubyGroups([][2]string{{"学", ""}, {"校", "がっこう"}, {"へ", ""}})
+	if len(got) != 2 || got[0] != [2]string{"学校", "がっこう"} {
+		t.Errorf("rubyGroups = %q", got)
+	}
+}
+
+func TestAnnotateMatchesProcess(t *testing.T) {
+	f, err := Parse(strings.NewReader(sampleASS), ASS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	furigana := Options{Ruby: RubyASS}
+	glosses := Options{Glosses: true}
+	files, vocab, err := Annotate(context.Background(), f, furigana, glosses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, opts := range []Options{furigana, glosses} {
+		want, wantVocab, err := Process(context.Background(), f, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if files[i].String() != want.String() {
+			t.Errorf("annotation %d:\n%s\nwant:\n%s", i, files[i], want)
+		}
+		if len(vocab) != len(wantVocab) || len(vocab[0].Words) != len(wantVocab[0].Words) {
+			t.Errorf("vocabulary %+v, want %+v", vocab, wantVocab)
+		}
+	}
+	if f.Cues[0].Text != "雨が、\n降った。" {
+		t.Errorf("input cue changed to %q", f.Cues[0].Text)
+	}
+}
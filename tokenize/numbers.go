@@ -0,0 +1,91 @@
+package tokenize
+
+import (
+	"strings"
+
+	"japaneseparse/furigana"
+	"japaneseparse/numerals"
+)
+
+// MergeNumberCounters merges runs of numeral tokens ("1", "万", "2", "千") and the counter
+// that follows them ("円") into a single token, and writes the generated reading (with
+// sound changes such as はっぷん or さんびゃく) into Reading and Pronunciation. Numerals
+// without a counter are merged and read on their own.
+func MergeNumberCounters(tokens []Token) []Token {
+	var out []Token
+	i := 0
+	for i < len(tokens) {
+		tk := tokens[i]
+		if !isNumberToken(tk) {
+			// kagome sometimes keeps digit+counter as one token, e.g. ７月
+			if num, rest := numerals.SplitNumeralPrefix(tk.Text); num != "" && hasDigit(num) && numerals.IsCounter(rest) {
+				if n, ok := numerals.Parse(num); ok {
+					setNumberReading(&tk, n, rest)
+				}
+			}
+			out = append(out, tk)
+			i++
+			continue
+		}
+
+		numText := ""
+		indices := []int{}
+		j := i
+		for j < len(tokens) && isNumberToken(tokens[j]) {
+			numText += tokens[j].Text
+			indices = append(indices, tokens[j].Start)
+			j++
+		}
+		n, ok := numerals.Parse(numText)
+		if !ok {
+			out = append(out, tokens[i:j]...)
+			i = j
+			continue
+		}
+		merged := tk
+		merged.Text = numText
+		merged.End = tokens[j-1].End
+		counter := ""
+		if j < len(tokens) && numerals.IsCounter(tokens[j].Text) {
+			counter = tokens[j].Text
+			merged.Text += counter
+			merged.End = tokens[j].End
+			indices = append(indices, tokens[j].Start)
+			j++
+		}
+		merged.Lemma = merged.Text
+		if len(indices) > 1 {
+			merged.MergedIndices = indices
+		}
+		setNumberReading(&merged, n, counter)
+		out = append(out, merged)
+		i = j
+	}
+	return out
+}
+
+// setNumberReading writes the value of n and the reading of n followed by counter into
+// tk and refreshes its furigana.
+func setNumberReading(tk *Token, n int64, counter string) {
+	tk.NumericValue = &n
+	r, _ := numerals.ReadWithCounter(n, counter)
+	tk.Reading = numerals.ToKatakana(r.String())
+	tk.Pronunciation = numerals.Pronunciation(r.String())
+	pairs, _ := furiganaPairs(tk.Text, tk.Reading, tk.DictionaryEntry)
+	tk.FuriganaText = furigana.FormatBracketsOnly(pairs)
+	tk.FuriganaLemma = furigana.FormatBracketsOnly(GetFuriganaString(tk.Lemma, tk.Reading))
+}
+
+// isNumberToken reports whether tk is a numeral token (名詞,数) made of numeral characters.
+func isNumberToken(tk Token) bool {
+	return strings.HasPrefix(tk.POS, "名詞,数") && numerals.IsNumeral(tk.Text)
+}
+
+func hasDigit(s string) bool {
+	for _, r := range s {
+		if (r >= '0' && r <= '9') || (r >= '０' && r <= '９') {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package tokenize
+
+import (
+	"context"
+	"log"
+	"runtime"
+	"sync"
+
+	"japaneseparse/ingest"
+)
+
+// PoolOptions configures a tokenizer Pool.
+type PoolOptions struct {
+	// Workers is the number of goroutines tokenizing in parallel; values below one
+	// default to runtime.NumCPU(). They all share the kagome tokenizer.
+	Workers int
+	// Ordered publishes results in the order sentences were received instead of the
+	// order they finish.
+	Ordered bool
+}
+
+// Pool tokenizes sentences with several workers. Create it with NewPool and Start it;
+// Stop shuts it down gracefully.
+type Pool struct {
+	opts     PoolOptions
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// poolJob is a sentence numbered in the order it was received.
+type poolJob struct {
+	seq      uint64
+	sentence ingest.Sentence
+}
+
+// poolResult is the outcome of one poolJob.
+type poolResult struct {
+	seq uint64
+	out Tokenized
+	err error
+}
+
+// NewPool returns a pool configured by opts.
+func NewPool(opts PoolOptions) *Pool {
+	if opts.Workers < 1 {
+		opts.Workers = runtime.NumCPU()
+	}
+	return &Pool{opts: opts, stop: make(chan struct{}), done: make(chan struct{})}
+}
+
+// Workers returns the number of workers.
+func (p *Pool) Workers() int {
+	return p.opts.Workers
+}
+
+// Start launches the workers, which read sentences from in and publish results to out
+// until in is closed, Stop is called or ctx is done. Sentences that fail to tokenize
+// are logged and skipped. Start must be called only once.
+func (p *Pool) Start(ctx context.Context, in <-chan ingest.Sentence, out chan<- Tokenized) {
+	jobs := make(chan poolJob, p.opts.Workers)
+	results := make(chan poolResult, p.opts.Workers)
+
+	// dispatcher: numbers sentences and hands them to the workers
+	go func() {
+		defer close(jobs)
+		var seq uint64
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ctx.Done():
+				return
+			case s, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case jobs <- poolJob{seq: seq, sentence: s}:
+					seq++
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < p.opts.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				toks, err := TokenizeSentence(ctx, j.sentence)
+				r := poolResult{seq: j.seq, out: Tokenized{Sentence: j.sentence, Tokens: toks}, err: err}
+				select {
+				case results <- r:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// collector: publishes results, reordering them if asked to
+	go func() {
+		defer close(p.done)
+		log.Printf("[TokenizerPool] Started %d workers (ordered=%v)", p.opts.Workers, p.opts.Ordered)
+		var next uint64
+		pending := make(map[uint64]poolResult)
+		for r := range results {
+			if !p.opts.Ordered {
+				if !publish(ctx, r, out) {
+					return
+				}
+				continue
+			}
+			pending[r.seq] = r
+			for {
+				r, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				next++
+				if !publish(ctx, r, out) {
+					return
+				}
+			}
+		}
+		log.Println("[TokenizerPool] All workers finished.")
+	}()
+}
+
+// publish sends a successful result to out. It reports false if ctx ended first.
+func publish(ctx context.Context, r poolResult, out chan<- Tokenized) bool {
+	if r.err != nil {
+		log.Printf("[TokenizerPool] Tokenize error for sentence ID=%s: %v", r.out.Sentence.ID, r.err)
+		return true
+	}
+	select {
+	case out <- r.out:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Stop stops taking new sentences and waits until every sentence already received has
+// been tokenized and published, so out must keep being drained. Sentences still in the
+// input channel stay there. Cancelling the context passed to Start ends the pool
+// without draining.
+func (p *Pool) Stop() {
+	p.stopOnce.Do(func() { close(p.stop) })
+	<-p.done
+}
+
+// Done is closed once the pool has shut down.
+func (p *Pool) Done() <-chan struct{} {
+	return p.done
+}
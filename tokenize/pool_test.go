@@ -0,0 +1,96 @@
+package tokenize
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"testing"
+
+	"japaneseparse/ingest"
+)
+
+var poolTexts = []string{
+	"今日は雨が降った。",
+	"午前8時40分に283世帯へ避難を呼びかけた。",
+	"彼は東京の大学で日本語を勉強しています。",
+	"猫が窓のそばで静かに眠っていた。",
+}
+
+func poolSentences(t testing.TB, n int) []ingest.Sentence {
+	sentences := make([]ingest.Sentence, n)
+	for i := range sentences {
+		s, err := ingest.NewSentence(poolTexts[i%len(poolTexts)])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sentences[i] = s
+	}
+	return sentences
+}
+
+func TestPoolOrderedDrain(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	sentences := poolSentences(t, 40)
+	in := make(chan ingest.Sentence, len(sentences))
+	for _, s := range sentences {
+		in <- s
+	}
+	out := make(chan Tokenized, len(sentences))
+	p := NewPool(PoolOptions{Workers: 4, Ordered: true})
+	p.Start(context.Background(), in, out)
+	for i := 0; i < len(sentences)/2; i++ {
+		r := <-out
+		if r.Sentence.ID != sentences[i].ID || len(r.Tokens) == 0 {
+			t.Fatalf("result %d: got sentence %s with %d tokens, want %s", i, r.Sentence.ID, len(r.Tokens), sentences[i].ID)
+		}
+	}
+	p.Stop()
+	close(out)
+
+	// everything taken from in before Stop was published, still in order
+	next := len(sentences) / 2
+	for r := range out {
+		if r.Sentence.ID != sentences[next].ID {
+			t.Fatalf("result %d: got sentence %s, want %s", next, r.Sentence.ID, sentences[next].ID)
+		}
+		next++
+	}
+	if next+len(in) != len(sentences) {
+		t.Errorf("%d published and %d left in queue, want %d in total", next, len(in), len(sentences))
+	}
+}
+
+// BenchmarkPool measures throughput per sentence by worker count; ns/op should drop
+// roughly with the number of workers up to the number of CPUs.
+func BenchmarkPool(b *testing.B) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	sentences := poolSentences(b, 256)
+	for _, workers := range []int{1, 2, 4, 8} {
+		for _, ordered := range []bool{false, true} {
+			b.Run(fmt.Sprintf("workers=%d/ordered=%v", workers, ordered), func(b *testing.B) {
+				in := make(chan ingest.Sentence, 64)
+				out := make(chan Tokenized, 64)
+				p := NewPool(PoolOptions{Workers: workers, Ordered: ordered})
+				p.Start(context.Background(), in, out)
+				b.ResetTimer()
+				go func() {
+					for i := 0; i < b.N; i++ {
+						in <- sentences[i%len(sentences)]
+					}
+					close(in)
+				}()
+				for i := 0; i < b.N; i++ {
+					<-out
+				}
+				b.StopTimer()
+				<-p.Done()
+			})
+		}
+	}
+}
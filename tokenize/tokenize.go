@@ -2,20 +2,15 @@ package tokenize
 
 import (
 	"context"
-	"encoding/json"
-	"encoding/xml"
 	"fmt"
-	"io"
-	"log"
-	"os"
 	"strings"
-	"sync"
-	"time"
-	"unicode/utf8"
+	"sync/atomic"
 
+	"japaneseparse/furigana"
 	"japaneseparse/ingest"
 	"japaneseparse/kanji"
 	"japaneseparse/model"
+	"japaneseparse/numerals"
 
 	"github.com/ikawaha/kagome-dict/ipa"
 	"github.com/ikawaha/kagome/v2/tokenizer"
@@ -30,394 +25,66 @@ type Tokenized struct {
 	Tokens   []Token
 }
 
-// TokenizedChan publishes tokenization results for downstream processing.
-var TokenizedChan chan Tokenized
-
 // kagome tokenizer instance (initialized in init)
 var kg *tokenizer.Tokenizer
 
-var (
-	kanjiReadingMap     map[rune][]string
-	kanjiReadingMapOnce sync.Once
-)
-
-// Kanjidic2Kanji represents a single kanji entry in Kanjidic2
-// Kanjidic2Root represents the root of the Kanjidic2 XML
-type Kanjidic2Kanji struct {
-	Literal        string `xml:"literal"`
-	ReadingMeaning struct {
-		RMGroup []struct {
-			Reading []struct {
-				Value string `xml:",chardata"`
-				Type  string `xml:"r_type,attr"`
-			} `xml:"reading"`
-		} `xml:"rmgroup"`
-	} `xml:"reading_meaning"`
-}
-
-type Kanjidic2Root struct {
-	Kanji []Kanjidic2Kanji `xml:"character"`
-}
-
-// Add this to the type definition:
-var _ = xml.Name{Local: "kanjidic2"}
-
 type DictionaryEntry = model.DictionaryEntry
 
-// InitKanjidic2 parses kanjidic2.xml and builds kanji→readings map
-func InitKanjidic2(path string) error {
-	var err error
-	kanjiReadingMapOnce.Do(func() {
-		kanjiReadingMap = make(map[rune][]string)
-		var loadedKanji []string
-		f, fileErr := os.Open(path)
-		if fileErr != nil {
-			log.Printf("Failed to open kanjidic2.xml: %v", fileErr)
-			return
-		}
-		defer f.Close()
-
-		// Use xml.Decoder to find <character> elements directly, skipping any wrapper
-		d := xml.NewDecoder(f)
-		for {
-			tok, tokenErr := d.Token()
-			if tokenErr == io.EOF {
-				break
-			}
-			if tokenErr != nil {
-				log.Printf("Failed to parse kanjidic2.xml: %v", tokenErr)
-				return
-			}
-			switch se := tok.(type) {
-			case xml.StartElement:
-				if se.Name.Local == "character" {
-					var k Kanjidic2Kanji
-					if decodeErr := d.DecodeElement(&k, &se); decodeErr != nil {
-						log.Printf("Failed to decode character: %v", decodeErr)
-						continue
-					}
-					if utf8.RuneCountInString(k.Literal) != 1 {
-						continue
-					}
-					var readings []string
-					for _, group := range k.ReadingMeaning.RMGroup {
-						for _, r := range group.Reading {
-							if r.Type == "ja_on" || r.Type == "ja_kun" {
-								readings = append(readings, r.Value)
-							}
-						}
-					}
-					kanjiRune, _ := utf8.DecodeRuneInString(k.Literal)
-					kanjiReadingMap[kanjiRune] = readings
-					if len(loadedKanji) < 10 {
-						loadedKanji = append(loadedKanji, fmt.Sprintf("%c: %v", kanjiRune, readings))
-					}
-					if kanjiRune == '秋' || kanjiRune == '田' {
-						log.Printf("Loaded readings for %c: %v", kanjiRune, readings)
-					}
-				}
-			}
-		}
-		log.Printf("First 10 kanji loaded: %v", loadedKanji)
-		log.Printf("Kanjidic2 loaded: %d kanji entries", len(kanjiReadingMap))
-	})
-	return err
-}
-
-// GetKanjiReadings returns readings for a kanji rune, with logging
-func GetKanjiReadings(r rune) []string {
-	if kanjiReadingMap == nil {
-		log.Printf("kanjiReadingMap is nil when looking up %c", r)
-		return nil
-	}
-	readings := kanjiReadingMap[r]
-	if readings == nil {
-		log.Printf("No readings found for kanji %c", r)
-	} else {
-		log.Printf("Readings for kanji %c: %v", r, readings)
-	}
-	return readings
-}
-
 func init() {
-	TokenizedChan = make(chan Tokenized, 100)
 	// initialize kagome tokenizer with the ipa dict and omit BOS/EOS
 	// ignore errors here for simplicity; Tokenize will return an error if tokenizer is nil
 	if t, err := tokenizer.New(ipa.Dict(), tokenizer.OmitBosEos()); err == nil {
 		kg = t
 	}
+	aligner.Store(alignerBox{furigana.Default()})
 }
 
 func isKanji(r rune) bool {
-	return r >= 0x4E00 && r <= 0x9FFF
+	return furigana.IsKanji(r)
 }
 
-// isKana returns true if rune is Hiragana or Katakana
-func isKana(r rune) bool {
-	return (r >= 0x3040 && r <= 0x309F) || (r >= 0x30A0 && r <= 0x30FF)
-}
+// aligner holds the furigana engine used for every token, boxed in alignerBox so its
+// dynamic type may change; see SetAligner.
+var aligner atomic.Value
 
-// rendaku helpers are provided by package kanji
-
-// getFuriganaString returns a slice of [kanji/kana, furigana] pairs for display.
-func getFuriganaString(surface, reading string) [][2]string {
-	result := make([][2]string, 0)
-	surfaceRunes := []rune(surface)
-	readingRunes := []rune(katakanaToHiragana(reading))
-	k := 0
-	for j := 0; j < len(surfaceRunes); j++ {
-		s := surfaceRunes[j]
-		if isKanji(s) {
-			// Greedy longest-match per kanji using kanjidic2 readings with normalized variants and rendaku
-			bestMatch := ""
-			bestLen := 0
-			kanjiReadings := kanji.GetKanjiReadings(s)
-			for _, kr := range kanjiReadings {
-				// generate normalized variants: full, prefix before '.', and without leading '-'
-				full := kanji.NormalizeReading(kr)
-				variants := []string{}
-				if full != "" {
-					variants = append(variants, full)
-				}
-				if idx := strings.IndexRune(kr, '.'); idx >= 0 {
-					pre := kr[:idx]
-					preNorm := kanji.NormalizeReading(pre)
-					if preNorm != "" {
-						// avoid duplicates
-						found := false
-						for _, v := range variants {
-							if v == preNorm {
-								found = true
-								break
-							}
-						}
-						if !found {
-							variants = append(variants, preNorm)
-						}
-					}
-				}
-				if strings.HasPrefix(kr, "-") {
-					noLead := kanji.NormalizeReading(strings.TrimPrefix(kr, "-"))
-					if noLead != "" {
-						found := false
-						for _, v := range variants {
-							if v == noLead {
-								found = true
-								break
-							}
-						}
-						if !found {
-							variants = append(variants, noLead)
-						}
-					}
-				}
+type alignerBox struct{ furigana.Aligner }
 
-				for _, v := range variants {
-					vRunes := []rune(v)
-					// normal match
-					if k+len(vRunes) <= len(readingRunes) && string(readingRunes[k:k+len(vRunes)]) == string(vRunes) {
-						if len(vRunes) > bestLen {
-							bestMatch = string(readingRunes[k : k+len(vRunes)])
-							bestLen = len(vRunes)
-						}
-					}
-					// rendaku match for non-first kanji
-					if j > 0 {
-						rForm := kanji.RendakuForm(v)
-						rRunes := []rune(rForm)
-						if k+len(rRunes) <= len(readingRunes) && string(readingRunes[k:k+len(rRunes)]) == rForm {
-							if len(rRunes) > bestLen {
-								bestMatch = string(readingRunes[k : k+len(rRunes)])
-								bestLen = len(rRunes)
-							}
-						}
-					}
-				}
-			}
-			if bestMatch != "" {
-				result = append(result, [2]string{string(s), bestMatch})
-				k += bestLen
-			} else {
-				// If no match, assign remaining reading to last kanji if it's the last kanji
-				isLastKanji := true
-				for jj := j + 1; jj < len(surfaceRunes); jj++ {
-					if isKanji(surfaceRunes[jj]) {
-						isLastKanji = false
-						break
-					}
-				}
-				if isLastKanji && k < len(readingRunes) {
-					result = append(result, [2]string{string(s), string(readingRunes[k:])})
-					k = len(readingRunes)
-				} else {
-					result = append(result, [2]string{string(s), ""})
-				}
-			}
-		} else if isKana(s) {
-			result = append(result, [2]string{string(s), ""})
-			if k < len(readingRunes) && readingRunes[k] == s {
-				k++
-			}
-		} else {
-			result = append(result, [2]string{string(s), ""})
-		}
-	}
-	// If there is leftover reading and no kanji left, append as plain text
-	kanjiLeft := false
-	for jj := len(surfaceRunes) - 1; jj >= 0; jj-- {
-		if isKanji(surfaceRunes[jj]) {
-			kanjiLeft = true
-			break
-		}
-	}
-	if !kanjiLeft && k < len(readingRunes) {
-		result = append(result, [2]string{"", string(readingRunes[k:])})
-	}
-	return result
+func currentAligner() furigana.Aligner {
+	return aligner.Load().(alignerBox).Aligner
 }
 
-// katakanaToHiragana converts katakana to hiragana for furigana display
-func katakanaToHiragana(s string) string {
-	runes := []rune(s)
-	for i, r := range runes {
-		if r >= 0x30A1 && r <= 0x30F6 {
-			runes[i] = r - 0x60
-		}
+// SetAligner replaces the furigana alignment strategy used by the tokenizer, e.g. a
+// furigana.Chain built with furigana.NewChain. A nil aligner restores the default. It
+// is safe to call while tokenizing.
+func SetAligner(a furigana.Aligner) {
+	if a == nil {
+		a = furigana.Default()
 	}
-	return string(runes)
+	aligner.Store(alignerBox{a})
 }
 
-// formatFuriganaDisplay formats the furigana pairs for display (e.g., [kanji|furigana] or HTML ruby tags)
-func formatFuriganaDisplay(pairs [][2]string) string {
-	out := ""
-	for _, pair := range pairs {
-		if pair[1] != "" {
-			out += "[" + pair[0] + "|" + pair[1] + "]"
-		} else {
-			out += pair[0]
-		}
-	}
-	return out
+// AlignerConfig describes the configured aligner, e.g. "override:0:…,kanjidic,numeric",
+// for cache keys.
+func AlignerConfig() string {
+	return fmt.Sprint(currentAligner())
 }
 
-// formatFuriganaBracketsOnly formats furigana so only kanji readings are in brackets, with non-kanji characters outside.
-func formatFuriganaBracketsOnly(pairs [][2]string) string {
-	out := ""
-	lastKanjiIdx := -1
-	for i, pair := range pairs {
-		if len(pair[0]) > 0 && isKanji([]rune(pair[0])[0]) {
-			lastKanjiIdx = i
-		}
-	}
-	// Assign remaining reading runes to last kanji if its furigana is empty
-	if lastKanjiIdx != -1 && pairs[lastKanjiIdx][1] == "" {
-		// Compute used reading runes
-		used := 0
-		for i, pair := range pairs {
-			if i == lastKanjiIdx {
-				break
-			}
-			used += len([]rune(pair[1]))
-		}
-		// Get remaining reading from context (not available here, so rely on getFuriganaString patch)
-		// For now, leave as is, since getFuriganaString should assign correctly
-	}
-	for _, pair := range pairs {
-		if len(pair[0]) == 0 {
-			continue // skip empty surface segments
-		}
-		if isKanji([]rune(pair[0])[0]) {
-			// Always output a bracketed block for every kanji, even if furigana is empty
-			out += "[" + pair[1] + "]"
-		} else if isKana([]rune(pair[0])[0]) {
-			out += pair[0]
-		} else if pair[0] != "" {
-			out += pair[0]
-		}
-	}
-	return out
+// furiganaPairs aligns reading to surface with the configured aligner. The bool reports
+// whether the word was aligned as a whole (jukujikun/ateji).
+func furiganaPairs(surface, reading string, entry DictionaryEntry) ([][2]string, bool) {
+	res, _ := currentAligner().Align(furigana.Input{Surface: surface, Reading: reading, Entry: entry})
+	return res.Pairs, res.Jukujikun
 }
 
-// Exported wrappers so other packages (like main) can reuse the improved logic
+// GetFuriganaString returns a slice of [kanji/kana, furigana] pairs for display.
 func GetFuriganaString(surface, reading string) [][2]string {
-	return getFuriganaString(surface, reading)
+	pairs, _ := furiganaPairs(surface, reading, DictionaryEntry{})
+	return pairs
 }
 
+// FormatFuriganaBracketsOnly formats furigana so only kanji readings are in brackets.
 func FormatFuriganaBracketsOnly(pairs [][2]string) string {
-	return formatFuriganaBracketsOnly(pairs)
-}
-
-// getFuriganaFromDictionary tries to align kanji and reading using JMdict entry if available
-func getFuriganaFromDictionary(surface string, entry DictionaryEntry) string {
-	if len(entry.Kanji) == 0 || len(entry.Readings) == 0 {
-		return ""
-	}
-	kanji := entry.Kanji[0]
-	reading := entry.Readings[0]
-	if kanji != surface {
-		// Only use dictionary furigana if kanji matches surface
-		return ""
-	}
-	// Use dictionary reading for word-level furigana grouping
-	surfaceRunes := []rune(kanji)
-	readingRunes := []rune(katakanaToHiragana(reading))
-	// Try to split reading proportionally by kanji/kana blocks
-	result := make([][2]string, 0)
-	kanjiCount := 0
-	for _, r := range surfaceRunes {
-		if isKanji(r) {
-			kanjiCount++
-		}
-	}
-	j, k := 0, 0
-	for j < len(surfaceRunes) {
-		s := surfaceRunes[j]
-		if isKanji(s) {
-			startK := k
-			remainingKanji := 0
-			for jj := j + 1; jj < len(surfaceRunes); jj++ {
-				if isKanji(surfaceRunes[jj]) {
-					remainingKanji++
-				}
-			}
-			remainingReading := len(readingRunes) - k
-			segLen := 1
-			if remainingKanji > 0 {
-				segLen = remainingReading / (remainingKanji + 1)
-				if segLen < 1 {
-					segLen = 1
-				}
-			} else {
-				segLen = remainingReading
-			}
-			endK := k + segLen
-			if endK > len(readingRunes) {
-				endK = len(readingRunes)
-			}
-			result = append(result, [2]string{"", string(readingRunes[startK:endK])})
-			k = endK
-			j++
-		} else if isKana(s) {
-			if k < len(readingRunes) && readingRunes[k] == s {
-				result = append(result, [2]string{"", string(s)})
-				k++
-			} else {
-				result = append(result, [2]string{"", ""})
-			}
-			j++
-		} else {
-			result = append(result, [2]string{"", ""})
-			j++
-		}
-	}
-	// Format as [segment] blocks
-	out := ""
-	for _, pair := range result {
-		if pair[1] != "" {
-			out += "[" + pair[1] + "]"
-		}
-	}
-	return out
+	return furigana.FormatBracketsOnly(pairs)
 }
 
 func convertKagomeTokens(ktoks []tokenizer.Token) []Token {
@@ -443,6 +110,7 @@ func convertKagomeTokens(ktoks []tokenizer.Token) []Token {
 			infType = features[4]
 			infForm = features[5]
 		}
+		textPairs, juku := furiganaPairs(kt.Surface, reading, DictionaryEntry{})
 		t := Token{
 			Text:           kt.Surface,
 			Lemma:          lemma,
@@ -454,8 +122,14 @@ func convertKagomeTokens(ktoks []tokenizer.Token) []Token {
 			TokenID:        tokenID,
 			InflectionType: infType,
 			InflectionForm: infForm,
-			FuriganaText:   formatFuriganaBracketsOnly(getFuriganaString(kt.Surface, reading)),
-			FuriganaLemma:  formatFuriganaBracketsOnly(getFuriganaString(lemma, reading)),
+			FuriganaText:   furigana.FormatBracketsOnly(textPairs),
+			FuriganaLemma:  furigana.FormatBracketsOnly(GetFuriganaString(lemma, reading)),
+			Jukujikun:      juku,
+		}
+		if strings.HasPrefix(pos, "名詞,数") && numerals.IsNumeral(kt.Surface) {
+			if v, ok := numerals.Parse(kt.Surface); ok {
+				t.NumericValue = &v
+			}
 		}
 		out = append(out, t)
 	}
@@ -479,16 +153,39 @@ func UpdateFuriganaFromDictionary(tokens []Token) []Token {
 				break
 			}
 		}
-		// Restore previous logic: use getFuriganaString for all tokens
+		// align through the shared furigana engine; the dictionary entry enables word-level ruby
 		if containsKanjiText {
-			tokens[i].FuriganaText = formatFuriganaBracketsOnly(getFuriganaString(tokens[i].Text, tokens[i].Reading))
+			pairs, juku := furiganaPairs(tokens[i].Text, tokens[i].Reading, tokens[i].DictionaryEntry)
+			tokens[i].FuriganaText = furigana.FormatBracketsOnly(pairs)
+			tokens[i].Jukujikun = juku
 		} else {
-			tokens[i].FuriganaText = formatFuriganaBracketsOnly(getFuriganaString(tokens[i].Text, tokens[i].Reading))
+			tokens[i].FuriganaText = furigana.FormatBracketsOnly(GetFuriganaString(tokens[i].Text, tokens[i].Reading))
+			// a kana-only surface has no kanji to read as a whole; drop any earlier flag
+			tokens[i].Jukujikun = false
 		}
 		if containsKanjiLemma {
-			tokens[i].FuriganaLemma = formatFuriganaBracketsOnly(getFuriganaString(tokens[i].Lemma, tokens[i].Reading))
+			tokens[i].FuriganaLemma = furigana.FormatBracketsOnly(GetFuriganaString(tokens[i].Lemma, tokens[i].Reading))
 		} else {
-			tokens[i].FuriganaLemma = formatFuriganaBracketsOnly(getFuriganaString(tokens[i].Lemma, tokens[i].Reading))
+			tokens[i].FuriganaLemma = furigana.FormatBracketsOnly(GetFuriganaString(tokens[i].Lemma, tokens[i].Reading))
+		}
+	}
+	return tokens
+}
+
+// UpdateFuriganaForProfile updates furigana like UpdateFuriganaFromDictionary, then clears
+// FuriganaText and FuriganaLemma on words whose kanji are all known to the reader profile.
+// A nil profile keeps furigana on every word.
+func UpdateFuriganaForProfile(tokens []Token, profile *kanji.ReaderProfile) []Token {
+	tokens = UpdateFuriganaFromDictionary(tokens)
+	if profile == nil {
+		return tokens
+	}
+	for i := range tokens {
+		if !profile.NeedsFurigana(tokens[i].Text) {
+			tokens[i].FuriganaText = ""
+		}
+		if !profile.NeedsFurigana(tokens[i].Lemma) {
+			tokens[i].FuriganaLemma = ""
 		}
 	}
 	return tokens
@@ -572,10 +269,38 @@ func Tokenize(ctx context.Context, text string) ([]Token, error) {
 		return nil, nil
 	}
 
-	ktoks := kg.Tokenize(text)
+	ktoks := kg.Analyze(text, modes[Mode()])
 	return convertKagomeTokens(ktoks), nil
 }
 
+// modes maps the names accepted by SetMode to kagome tokenization modes.
+var modes = map[string]tokenizer.TokenizeMode{
+	"normal":   tokenizer.Normal,
+	"search":   tokenizer.Search,
+	"extended": tokenizer.Extended,
+}
+
+// mode holds the name of the mode used by Tokenize.
+var mode atomic.Value
+
+// SetMode selects the kagome mode used by Tokenize: "normal" (the default), "search"
+// (splits long compounds) or "extended" (also splits unknown words into characters).
+func SetMode(name string) error {
+	if _, ok := modes[name]; !ok {
+		return fmt.Errorf("unknown tokenizer mode %q (want normal, search or extended)", name)
+	}
+	mode.Store(name)
+	return nil
+}
+
+// Mode returns the name of the mode used by Tokenize.
+func Mode() string {
+	if name, ok := mode.Load().(string); ok {
+		return name
+	}
+	return "normal"
+}
+
 // TokenizeModes runs kagome.Analyze in Normal, Search and Extended modes and returns
 // a map from mode name to the resulting tokens. Useful to compare segmentations.
 func TokenizeModes(ctx context.Context, text string) (map[string][]Token, error) {
@@ -623,189 +348,15 @@ func TokenizeStream(ctx context.Context, text string) (<-chan Token, <-chan erro
 	return out, errs
 }
 
-// StartTokenizer launches a goroutine that consumes Sentence from IngestChan,
-// tokenizes them and publishes Tokenized results to TokenizedChan.
-func StartTokenizer(ctx context.Context) {
-	go func() {
-		log.Println("[StartTokenizer] Goroutine started, waiting for sentences...")
-		for {
-			select {
-			case <-ctx.Done():
-				log.Println("[StartTokenizer] Context done, exiting goroutine.")
-				return
-			case s := <-ingest.IngestChan:
-				log.Printf("[StartTokenizer] Received sentence: ID=%s, Text=%s", s.ID, s.Text)
-				toks, err := Tokenize(ctx, s.Text)
-				if err != nil {
-					log.Printf("[StartTokenizer] Tokenize error: %v", err)
-					continue
-				}
-				log.Printf("[StartTokenizer] Tokenized %d tokens for sentence ID=%s", len(toks), s.ID)
-				select {
-				case <-ctx.Done():
-					log.Println("[StartTokenizer] Context done after tokenization, exiting goroutine.")
-					return
-				case TokenizedChan <- Tokenized{Sentence: s, Tokens: toks}:
-					log.Printf("[StartTokenizer] Published tokenized result for sentence ID=%s", s.ID)
-				}
-			}
-		}
-	}()
-}
-
-// logFuriganaAlignment logs the alignment process to a JSON file for debugging
-func logFuriganaAlignment(tokenText, tokenReading string, steps []map[string]interface{}) {
-	// Use a unique filename: include reading, PID, and a random number for uniqueness
-	randomPart := fmt.Sprintf("%d", time.Now().UnixNano())
-	filename := fmt.Sprintf("logs/%s_%s_%s_furigana.json", tokenText, tokenReading, randomPart)
-	data, err := json.MarshalIndent(steps, "", "  ")
-	if err != nil {
-		log.Printf("[FURIGANA] Failed to marshal alignment log for %s: %v", tokenText, err)
-		return
-	}
-	err = os.WriteFile(filename, data, 0644)
+// TokenizeSentence tokenizes the normalized text of s and reports token positions
+// against the raw input.
+func TokenizeSentence(ctx context.Context, s ingest.Sentence) ([]Token, error) {
+	toks, err := Tokenize(ctx, s.Text)
 	if err != nil {
-		log.Printf("[FURIGANA] Failed to write alignment log for %s: %v", tokenText, err)
-	} else {
-		log.Printf("[FURIGANA] Alignment log written: %s", filename)
-	}
-}
-
-// alignFuriganaAccurate splits reading for each kanji by remaining kana and kanji count, using Kanjidic2 readings for kanji
-func alignFuriganaAccurate(surface, reading string) [][2]string {
-	surfaceRunes := []rune(surface)
-	readingRunes := []rune(katakanaToHiragana(reading))
-	var result [][2]string
-	j, k := 0, 0
-	for j < len(surfaceRunes) {
-		s := surfaceRunes[j]
-		if isKanji(s) {
-			// Find the best matching reading for this kanji
-			bestMatch := ""
-			bestLen := 0
-			kanjiReadings := kanji.GetKanjiReadings(s)
-			for _, kr := range kanjiReadings {
-				// normalize and try useful variants
-				full := kanji.NormalizeReading(kr)
-				variants := []string{}
-				if full != "" {
-					variants = append(variants, full)
-				}
-				if idx := strings.IndexRune(kr, '.'); idx >= 0 {
-					pre := kr[:idx]
-					preNorm := kanji.NormalizeReading(pre)
-					if preNorm != "" {
-						found := false
-						for _, v := range variants {
-							if v == preNorm {
-								found = true
-								break
-							}
-						}
-						if !found {
-							variants = append(variants, preNorm)
-						}
-					}
-				}
-				if strings.HasPrefix(kr, "-") {
-					noLead := kanji.NormalizeReading(strings.TrimPrefix(kr, "-"))
-					if noLead != "" {
-						found := false
-						for _, v := range variants {
-							if v == noLead {
-								found = true
-								break
-							}
-						}
-						if !found {
-							variants = append(variants, noLead)
-						}
-					}
-				}
-				for _, v := range variants {
-					vRunes := []rune(v)
-					if k+len(vRunes) <= len(readingRunes) && string(readingRunes[k:k+len(vRunes)]) == string(vRunes) {
-						if len(vRunes) > bestLen {
-							bestMatch = string(readingRunes[k : k+len(vRunes)])
-							bestLen = len(vRunes)
-						}
-					}
-					// try rendaku for non-first kanji
-					if j > 0 {
-						rForm := kanji.RendakuForm(v)
-						rRunes := []rune(rForm)
-						if k+len(rRunes) <= len(readingRunes) && string(readingRunes[k:k+len(rRunes)]) == rForm {
-							if len(rRunes) > bestLen {
-								bestMatch = string(readingRunes[k : k+len(rRunes)])
-								bestLen = len(rRunes)
-							}
-						}
-					}
-				}
-			}
-			if bestMatch != "" {
-				result = append(result, [2]string{"", bestMatch})
-				k += bestLen
-			} else {
-				// No match: if this is the last kanji and there are remaining reading runes, assign them as furigana (rendaku fix)
-				isLastKanji := true
-				for jj := j + 1; jj < len(surfaceRunes); jj++ {
-					if isKanji(surfaceRunes[jj]) {
-						isLastKanji = false
-						break
-					}
-				}
-				if isLastKanji && k < len(readingRunes) {
-					result = append(result, [2]string{string(s), string(readingRunes[k:])})
-					k = len(readingRunes)
-				} else {
-					result = append(result, [2]string{string(s), ""})
-				}
-			}
-			j++
-		} else if isKana(s) {
-			if k < len(readingRunes) && readingRunes[k] == s {
-				result = append(result, [2]string{string(s), ""})
-				k++
-			} else {
-				result = append(result, [2]string{string(s), ""})
-			}
-			j++
-		} else {
-			result = append(result, [2]string{string(s), ""})
-			j++
-		}
-	}
-	// Only append remaining reading if there are no kanji left in surface
-	kanjiLeft := false
-	for jj := j; jj < len(surfaceRunes); jj++ {
-		if isKanji(surfaceRunes[jj]) {
-			kanjiLeft = true
-			break
-		}
+		return nil, err
 	}
-	if !kanjiLeft && k < len(readingRunes) {
-		result = append(result, [2]string{"", string(readingRunes[k:])})
+	for i := range toks {
+		toks[i].Start, toks[i].End = s.OriginalSpan(toks[i].Start, toks[i].End)
 	}
-	return result
+	return toks, nil
 }
-
-// formatFuriganaDisplayAccurate formats furigana so only kanji get [kanji|furigana], kana are plain
-func formatFuriganaDisplayAccurate(pairs [][2]string) string {
-	out := ""
-	for _, pair := range pairs {
-		if len(pair[0]) == 0 {
-			continue
-		}
-		if isKanji([]rune(pair[0])[0]) {
-			out += "[" + pair[1] + "]"
-		} else {
-			out += pair[0]
-		}
-	}
-	return out
-}
-
-// normalizeReading removes non-kana characters (like '.' or '-') and
-// converts katakana to hiragana so kanjidic readings like "い.り" match "いり".
-// use kanji.NormalizeReading
@@ -0,0 +1,84 @@
+package tokenize
+
+import (
+	"context"
+	"testing"
+
+	"japaneseparse/kanji"
+)
+
+func TestUpdateFuriganaForProfile(t *testing.T) {
+	tokens := func() []Token {
+		toks, err := Tokenize(context.Background(), "雨が降った")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return toks
+	}
+	furiganaOf := func(toks []Token, surface string) string {
+		for _, tk := range toks {
+			if tk.Text == surface {
+				return tk.FuriganaText
+			}
+		}
+		t.Fatalf("no token %q in %v", surface, toks)
+		return ""
+	}
+
+	all := UpdateFuriganaForProfile(tokens(), nil)
+	if furiganaOf(all, "雨") == "" {
+		t.Error("nil profile dropped the furigana of 雨")
+	}
+	known := UpdateFuriganaForProfile(tokens(), kanji.NewKnownKanjiProfile("雨"))
+	if f := furiganaOf(known, "雨"); f != "" {
+		t.Errorf("known word 雨 kept furigana %q", f)
+	}
+	if furiganaOf(known, "降っ") == "" {
+		t.Error("unknown word 降っ lost its furigana")
+	}
+}
+
+func TestJukujikunFlag(t *testing.T) {
+	cases := []struct {
+		text, reading string
+		want          bool
+	}{
+		{"今日", "キョウ", true},
+		{"大人", "オトナ", true},
+		{"学校", "ガッコウ", false}, // read kanji by kanji
+		{"きょう", "キョウ", false},
+	}
+	for _, c := range cases {
+		toks := UpdateFuriganaFromDictionary([]Token{{Text: c.text, Lemma: c.text, Reading: c.reading}})
+		if toks[0].Jukujikun != c.want {
+			t.Errorf("%s (%s): Jukujikun = %v, want %v", c.text, c.reading, toks[0].Jukujikun, c.want)
+		}
+	}
+
+	// a token re-used after its text became kana loses the flag
+	toks := UpdateFuriganaFromDictionary([]Token{{Text: "今日", Lemma: "今日", Reading: "キョウ"}})
+	toks[0].Text = "きょう"
+	if toks = UpdateFuriganaFromDictionary(toks); toks[0].Jukujikun {
+		t.Error("kana-only token still flagged as jukujikun")
+	}
+}
+
+func TestMergeNumberCountersLargeNumber(t *testing.T) {
+	// 17 digits, past the largest unit the reader used to know (兆)
+	toks, err := Tokenize(context.Background(), "人口は10000000000000000人だ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var merged *Token
+	for _, tk := range MergeNumberCounters(toks) {
+		if tk.NumericValue != nil {
+			merged = &tk
+		}
+	}
+	if merged == nil || merged.Text != "10000000000000000人" || *merged.NumericValue != 1e16 {
+		t.Fatalf("merged = %+v", merged)
+	}
+	if merged.Reading != "イッケイニン" {
+		t.Errorf("reading = %s, want イッケイニン", merged.Reading)
+	}
+}
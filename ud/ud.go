@@ -0,0 +1,173 @@
+// Package ud maps the IPA part-of-speech strings and inflection labels of tokens to
+// Universal Dependencies UPOS tags and features, following the conventions of the UD
+// Japanese treebanks where the IPA tags alone are ambiguous.
+package ud
+
+import (
+	"strings"
+
+	"japaneseparse/model"
+)
+
+// Features are the UD features derived for a word. Empty fields are not set.
+type Features struct {
+	Polarity string `json:"Polarity,omitempty"` // Neg
+	Polite   string `json:"Polite,omitempty"`   // Form
+	Tense    string `json:"Tense,omitempty"`    // Past or Pres
+	VerbForm string `json:"VerbForm,omitempty"` // Fin, Part or Conv
+}
+
+// String returns the features in CoNLL-U notation, e.g. "Polarity=Neg|Polite=Form",
+// or "" when none is set.
+func (f Features) String() string {
+	var fs []string
+	for _, kv := range [][2]string{{"Polarity", f.Polarity}, {"Polite", f.Polite}, {"Tense", f.Tense}, {"VerbForm", f.VerbForm}} {
+		if kv[1] != "" {
+			fs = append(fs, kv[0]+"="+kv[1])
+		}
+	}
+	return strings.Join(fs, "|")
+}
+
+// pos splits an IPA POS string into its four fields.
+func pos(t model.Token) [4]string {
+	var f [4]string
+	for i, s := range strings.SplitN(t.POS, ",", 4) {
+		f[i] = s
+	}
+	return f
+}
+
+// UPOS returns the UPOS tag of toks[i]. The neighbouring tokens settle the cases the
+// IPA tag leaves open, such as a サ変 noun followed by する.
+func UPOS(toks []model.Token, i int) string {
+	t := toks[i]
+	p := pos(t)
+	switch p[0] {
+	case "名詞":
+		switch {
+		case p[1] == "固有名詞":
+			return "PROPN"
+		case p[1] == "代名詞":
+			return "PRON"
+		case p[1] == "数":
+			return "NUM"
+		case p[1] == "形容動詞語幹":
+			return "ADJ"
+		case p[2] == "助動詞語幹": // よう in ようだ, そう in そうだ
+			return "AUX"
+		case p[1] == "サ変接続" && i+1 < len(toks) && toks[i+1].Lemma == "する":
+			return "VERB"
+		case p[1] == "非自立" && (t.Lemma == "の" || t.Lemma == "ん"):
+			return "SCONJ" // nominalizer, as in 行くのだ
+		}
+		return "NOUN"
+	case "動詞":
+		switch {
+		case p[1] == "非自立", p[1] == "接尾":
+			return "AUX" // いる in ている, れる, させる
+		case t.Lemma == "する" && i > 0 && UPOS(toks, i-1) == "VERB" && pos(toks[i-1])[1] == "サ変接続":
+			return "AUX" // the light verb of 勉強する
+		}
+		return "VERB"
+	case "形容詞":
+		if p[1] == "非自立" {
+			return "AUX" // にくい, ほしい after て
+		}
+		return "ADJ"
+	case "助動詞":
+		return "AUX" // だ, です, た, ない, ます, う
+	case "助詞":
+		switch p[1] {
+		case "接続助詞":
+			return "SCONJ" // て, が in 雨だが, と in 春になると
+		case "並立助詞":
+			return "CCONJ" // と in 犬と猫, や
+		case "終助詞", "副助詞／並立助詞／終助詞":
+			return "PART" // よ, ね, か
+		}
+		return "ADP" // 格助詞 が, を, と; 係助詞 は, も; 連体化 の
+	case "副詞":
+		return "ADV"
+	case "接続詞":
+		return "CCONJ" // が, そして at the start of a sentence
+	case "連体詞":
+		if strings.HasSuffix(t.Lemma, "の") || strings.HasSuffix(t.Lemma, "んな") {
+			return "DET" // この, そんな
+		}
+		return "ADJ" // 大きな, いわゆる
+	case "感動詞", "フィラー":
+		return "INTJ"
+	case "接頭詞":
+		return "NOUN"
+	case "記号":
+		if p[1] == "句点" || p[1] == "読点" || strings.HasPrefix(p[1], "括弧") {
+			return "PUNCT"
+		}
+		return "SYM"
+	}
+	return "X"
+}
+
+// Feats returns the features of toks[i]. A merged token (one with Auxiliaries) takes
+// them from the whole verb group, so 食べませんでした is polite, negative and past.
+func Feats(toks []model.Token, i int) Features {
+	t := toks[i]
+	pieces := append([]model.Token{t}, t.Auxiliaries...)
+	var f Features
+	var last *model.Token // last inflecting piece
+	for k := range pieces {
+		p := &pieces[k]
+		if p.InflectionType == "" || p.InflectionType == "*" {
+			continue
+		}
+		last = p
+		if p.Lemma == "ます" || p.Lemma == "です" {
+			f.Polite = "Form"
+		}
+		if negative(*p) {
+			f.Polarity = "Neg"
+		}
+	}
+	if last == nil {
+		return f
+	}
+
+	switch {
+	case last.InflectionType == "特殊・タ":
+		f.Tense = "Past" // た, and だ after 読ん
+	case last.InflectionForm == "基本形" && last.InflectionType != "不変化型":
+		f.Tense = "Pres" // not う or ん, which carry no tense
+	}
+
+	var next *model.Token
+	if i+1 < len(toks) {
+		next = &toks[i+1]
+	}
+	switch {
+	case next != nil && pos(*next)[1] == "接続助詞" && converbal[next.Lemma]:
+		f.VerbForm = "Conv"
+	case last.InflectionForm == "基本形" || strings.HasPrefix(last.InflectionForm, "命令"):
+		f.VerbForm = "Fin"
+		if next != nil && pos(*next)[0] == "名詞" && UPOS(toks, i+1) != "SCONJ" && UPOS(toks, i+1) != "AUX" {
+			f.VerbForm = "Part" // modifies the noun, as 読む in 読む人
+		}
+	}
+	return f
+}
+
+// converbal are the conjunctive particles that turn the preceding form into a converb.
+var converbal = map[string]bool{"て": true, "で": true, "ながら": true, "つつ": true, "ば": true}
+
+// negative reports whether t negates: ない, ぬ and ん, and the adjective ない.
+func negative(t model.Token) bool {
+	switch {
+	case t.InflectionType == "特殊・ナイ", t.InflectionType == "特殊・ヌ":
+		return true
+	case strings.HasPrefix(t.POS, "助動詞") && t.Lemma == "ん":
+		return true
+	case strings.HasPrefix(t.POS, "形容詞") && (t.Lemma == "ない" || t.Lemma == "無い"):
+		return true
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package ud
+
+import (
+	"context"
+	"testing"
+
+	"japaneseparse/model"
+	"japaneseparse/tokenize"
+)
+
+func tokens(t *testing.T, text string, merged bool) []model.Token {
+	t.Helper()
+	toks, err := tokenize.Tokenize(context.Background(), text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged {
+		toks = tokenize.MergeVerbAuxiliaries(toks)
+	}
+	return toks
+}
+
+// find returns the index of the nth (0-based) token with the given surface.
+func find(t *testing.T, toks []model.Token, surface string, nth int) int {
+	t.Helper()
+	for i, tok := range toks {
+		if tok.Text == surface {
+			if nth == 0 {
+				return i
+			}
+			nth--
+		}
+	}
+	t.Fatalf("no token %q in %v", surface, toks)
+	return -1
+}
+
+func TestUPOSAmbiguous(t *testing.T) {
+	cases := []struct {
+		text, surface string
+		nth           int
+		want          string
+	}{
+		{"犬と猫が好きだ。", "と", 0, "CCONJ"},  // 並立助詞
+		{"友達と遊ぶ。", "と", 0, "ADP"},      // 格助詞
+		{"行くと言った。", "と", 0, "ADP"},     // quotative 格助詞
+		{"春になると暖かい。", "と", 0, "SCONJ"}, // 接続助詞
+		{"雨が降った。", "が", 0, "ADP"},      // 格助詞
+		{"雨だが行く。", "が", 0, "SCONJ"},    // 接続助詞
+		{"が、行く。", "が", 0, "CCONJ"},     // 接続詞
+		{"本だ。", "だ", 0, "AUX"},         // copula
+		{"読んだ。", "だ", 0, "AUX"},        // past tense
+		{"勉強する。", "勉強", 0, "VERB"},     // サ変 noun with する
+		{"勉強する。", "する", 0, "AUX"},
+		{"勉強が好きだ。", "勉強", 0, "NOUN"},
+		{"静かな部屋。", "静か", 0, "ADJ"},
+		{"食べている。", "いる", 0, "AUX"},
+		{"東京に行く。", "東京", 0, "PROPN"},
+		{"この本。", "この", 0, "DET"},
+		{"行くのだ。", "の", 0, "SCONJ"},
+		{"いいよ。", "よ", 0, "PART"},
+		{"雨。", "。", 0, "PUNCT"},
+	}
+	for _, c := range cases {
+		toks := tokens(t, c.text, false)
+		if got := UPOS(toks, find(t, toks, c.surface, c.nth)); got != c.want {
+			t.Errorf("%s: %s = %s, want %s", c.text, c.surface, got, c.want)
+		}
+	}
+}
+
+func TestFeats(t *testing.T) {
+	cases := []struct {
+		text, surface string
+		merged        bool
+		want          string
+	}{
+		{"食べませんでした。", "食べませんでした", true, "Polarity=Neg|Polite=Form|Tense=Past|VerbForm=Fin"},
+		{"本です。", "です", false, "Polite=Form|Tense=Pres|VerbForm=Fin"},
+		{"本だ。", "だ", false, "Tense=Pres|VerbForm=Fin"},
+		{"読んだ。", "読んだ", true, "Tense=Past|VerbForm=Fin"},
+		{"食べない。", "食べない", true, "Polarity=Neg|Tense=Pres|VerbForm=Fin"},
+		{"金がない。", "ない", false, "Polarity=Neg|Tense=Pres|VerbForm=Fin"},
+		{"食べて寝る。", "食べ", false, "VerbForm=Conv"},
+		{"読む人。", "読む", false, "Tense=Pres|VerbForm=Part"},
+		{"行こう。", "行こう", true, "VerbForm=Fin"},
+		{"雨。", "雨", false, ""},
+	}
+	for _, c := range cases {
+		toks := tokens(t, c.text, c.merged)
+		if got := Feats(toks, find(t, toks, c.surface, 0)).String(); got != c.want {
+			t.Errorf("%s: %s = %q, want %q", c.text, c.surface, got, c.want)
+		}
+	}
+}